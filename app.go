@@ -19,6 +19,7 @@ import (
 	"edex-ui-golang/internal/system"
 	"edex-ui-golang/internal/terminal"
 	"github.com/lxn/walk"
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App struct
@@ -31,6 +32,7 @@ type App struct {
 	mu             sync.RWMutex
 }
 
+// r 启动完成后的渲染器状态，读写需持有 App.mu
 var r *Boot
 
 // NewApp creates a new App application struct
@@ -93,11 +95,20 @@ func (a *App) startup(ctx context.Context) {
 
 	// 初始化终端管理器
 	a.terminalMgr = terminal.NewManager(a.settingsMgr.GetSettings())
+	a.terminalMgr.SetEventEmitter(func(event string, data interface{}) {
+		wailsruntime.EventsEmit(a.ctx, event, data)
+	})
+	if err := a.reloadShellProfiles(); err != nil {
+		log.Printf("加载 shell 配置失败: %v", err)
+	}
 	if err := a.terminalMgr.InitializeTerminal(); err != nil {
 		log.Printf("初始化终端失败: %v", err)
 		a.showErrorDialog("终端初始化错误", fmt.Sprintf("无法初始化终端：\n\n%v\n\n终端功能可能无法使用。", err))
 		return
 	}
+	if err := a.reloadOutputTriggers(); err != nil {
+		log.Printf("加载输出触发规则失败: %v", err)
+	}
 
 	// 初始化系统信息提供者
 	a.systemProvider = system.NewInfoProvider()
@@ -105,9 +116,13 @@ func (a *App) startup(ctx context.Context) {
 	// 初始化网络管理器
 	a.networkMgr = network.NewManager()
 
-	r = NewBoot()
-	r.loadConfig()
-	r.loadTheme(a.settingsMgr.GetSettings().Theme)
+	// 加载完成后再发布，前端调用的导出等方法会并发读取主题
+	boot := NewBoot()
+	boot.loadConfig()
+	boot.loadTheme(a.settingsMgr.GetSettings().Theme)
+	a.mu.Lock()
+	r = boot
+	a.mu.Unlock()
 
 	log.Println("应用启动完成")
 }
@@ -241,6 +256,61 @@ func (a *App) UpdateShortcut(shortcut models.Shortcut) error {
 	return a.settingsMgr.UpdateShortcut(shortcut)
 }
 
+// GetOutputTriggers 获取所有终端输出触发规则
+func (a *App) GetOutputTriggers() ([]models.OutputTrigger, error) {
+	return a.settingsMgr.GetTriggers()
+}
+
+// AddOutputTrigger 添加终端输出触发规则，返回带有 ID 的规则
+func (a *App) AddOutputTrigger(trigger models.OutputTrigger) (models.OutputTrigger, error) {
+
+	if err := terminal.ValidateTrigger(trigger); err != nil {
+		return trigger, err
+	}
+	trigger, err := a.settingsMgr.AddTrigger(trigger)
+	if err != nil {
+		return trigger, err
+	}
+	return trigger, a.reloadOutputTriggers()
+}
+
+// UpdateOutputTrigger 更新终端输出触发规则
+func (a *App) UpdateOutputTrigger(trigger models.OutputTrigger) error {
+
+	if err := terminal.ValidateTrigger(trigger); err != nil {
+		return err
+	}
+	if err := a.settingsMgr.UpdateTrigger(trigger); err != nil {
+		return err
+	}
+	return a.reloadOutputTriggers()
+}
+
+// DeleteOutputTrigger 删除终端输出触发规则
+func (a *App) DeleteOutputTrigger(id string) error {
+
+	if err := a.settingsMgr.DeleteTrigger(id); err != nil {
+		return err
+	}
+	return a.reloadOutputTriggers()
+}
+
+// reloadOutputTriggers 将保存的触发规则应用到终端，无效的规则被跳过
+func (a *App) reloadOutputTriggers() error {
+
+	if a.terminalMgr == nil {
+		return nil
+	}
+	triggers, err := a.settingsMgr.GetTriggers()
+	if err != nil {
+		return err
+	}
+	if invalid := a.terminalMgr.SetTriggers(triggers); len(invalid) > 0 {
+		log.Printf("%d 条触发规则无效，未生效", len(invalid))
+	}
+	return nil
+}
+
 // HandleShortcut 处理快捷键动作
 func (a *App) HandleShortcut(action string) (string, error) {
 	switch action {
@@ -344,13 +414,12 @@ func (a *App) UpdateSettings(settingsData map[string]interface{}) error {
 
 	newSettings := &models.Settings{}
 
-	// 从 map 中提取设置值
-	if val, ok := settingsData["shell"].(string); ok {
-		newSettings.Shell = val
-	}
-	if val, ok := settingsData["shellArgs"].(string); ok {
-		newSettings.ShellArgs = val
+	// 旧版 shell 设置已由 shell 配置取代，保留原值供首次生成 shell 配置
+	if current := a.settingsMgr.GetSettings(); current != nil {
+		newSettings.Shell, newSettings.ShellArgs = current.Shell, current.ShellArgs
 	}
+
+	// 从 map 中提取设置值
 	if val, ok := settingsData["cwd"].(string); ok {
 		newSettings.Cwd = val
 	}
@@ -420,9 +489,54 @@ func (a *App) UpdateSettings(settingsData map[string]interface{}) error {
 	if val, ok := settingsData["experimentalFeatures"].(bool); ok {
 		newSettings.ExperimentalFeatures = val
 	}
+	if val, ok := settingsData["scrollbackSize"].(float64); ok {
+		newSettings.ScrollbackSize = int(val)
+	}
+	if val, ok := settingsData["allowRemoteTerminal"].(bool); ok {
+		newSettings.AllowRemoteTerminal = val
+	}
+	if val, ok := settingsData["terminalCompression"].(bool); ok {
+		newSettings.TerminalCompression = val
+	}
+	if val, ok := settingsData["notifyLongCommands"].(bool); ok {
+		newSettings.NotifyLongCommands = val
+	}
+	if val, ok := settingsData["longCommandThreshold"].(float64); ok {
+		newSettings.LongCommandThreshold = int(val)
+	}
+	if val, ok := settingsData["longCommandSound"].(bool); ok {
+		newSettings.LongCommandSound = val
+	}
+	if val, ok := settingsData["maxSessions"].(float64); ok {
+		newSettings.MaxSessions = int(val)
+	}
+	if val, ok := settingsData["containerSocket"].(string); ok {
+		newSettings.ContainerSocket = val
+	}
+	if val, ok := settingsData["terminalAllowedOrigins"].([]interface{}); ok {
+		for _, origin := range val {
+			if value, ok := origin.(string); ok && value != "" {
+				newSettings.TerminalAllowedOrigins = append(newSettings.TerminalAllowedOrigins, value)
+			}
+		}
+	}
+
+	// 校验额外环境变量，避免保存后终端无法启动
+	if _, err := terminal.ParseEnv(newSettings.Env); err != nil {
+		return fmt.Errorf("环境变量设置无效: %v", err)
+	}
 
 	// 保存设置
-	return a.settingsMgr.SaveSettings(newSettings)
+	if err := a.settingsMgr.SaveSettings(newSettings); err != nil {
+		return err
+	}
+
+	// 提醒设置与会话数量上限立即生效，其余终端设置在重启后生效
+	if a.terminalMgr != nil {
+		a.terminalMgr.SetCommandNotify(newSettings)
+		a.terminalMgr.SetMaxSessions(newSettings.MaxSessions)
+	}
+	return nil
 }
 
 // getAvailableKeyboards 获取可用的键盘布局
@@ -626,8 +740,14 @@ func (a *App) GetExternalIPWithGeo() *models.IPGeoInfo {
 	return a.networkMgr.GetExternalIPWithGeo()
 }
 
-// GetTheme 获取主题信息
+// GetTheme 获取主题信息，启动完成前返回 nil
 func (a *App) GetTheme() *Theme {
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if r == nil {
+		return nil
+	}
 	return r.theme
 }
 func (a *App) Log(class string, msg string) {
@@ -731,14 +851,432 @@ func (a *App) ResolveFilePath(dirPath, filename string) (string, error) {
 	return a.settingsMgr.ResolveFilePath(dirPath, filename)
 }
 
+// 终端会话相关函数
+
+// GetTerminalConnection 获取终端 WebSocket 的连接地址与本次启动的令牌
+func (a *App) GetTerminalConnection() (*models.TerminalConnection, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.ConnectionInfo()
+}
+
+// CreateTerminalSession 创建新的终端会话（标签页），可指定 shell 配置或 shell 与工作目录
+func (a *App) CreateTerminalSession(opts models.SessionOptions) (*models.TerminalSessionInfo, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.CreateSession(opts)
+}
+
+// GetShellProfiles 获取所有 shell 配置，包含解析后的路径与无效原因
+func (a *App) GetShellProfiles() (models.ShellProfiles, error) {
+
+	if a.terminalMgr == nil {
+		return a.settingsMgr.GetShellProfiles()
+	}
+	return a.terminalMgr.ShellProfiles(), nil
+}
+
+// SaveShellProfile 新增或更新 shell 配置，返回带有 ID 的配置
+func (a *App) SaveShellProfile(profile models.ShellProfile) (models.ShellProfile, error) {
+
+	if err := terminal.ValidateShellProfile(profile); err != nil {
+		return profile, err
+	}
+	profile, err := a.settingsMgr.SaveShellProfile(profile)
+	if err != nil {
+		return profile, err
+	}
+	return profile, a.reloadShellProfiles()
+}
+
+// DeleteShellProfile 删除 shell 配置
+func (a *App) DeleteShellProfile(id string) error {
+
+	if err := a.settingsMgr.DeleteShellProfile(id); err != nil {
+		return err
+	}
+	return a.reloadShellProfiles()
+}
+
+// SetDefaultShellProfile 设置新建标签页默认使用的 shell 配置
+func (a *App) SetDefaultShellProfile(id string) error {
+
+	if err := a.settingsMgr.SetDefaultShellProfile(id); err != nil {
+		return err
+	}
+	return a.reloadShellProfiles()
+}
+
+// reloadShellProfiles 将保存的 shell 配置应用到终端
+func (a *App) reloadShellProfiles() error {
+
+	if a.terminalMgr == nil {
+		return nil
+	}
+	profiles, err := a.settingsMgr.GetShellProfiles()
+	if err != nil {
+		// 配置文件无法读取时使用由旧版设置生成的配置，保证终端仍然可用
+		a.terminalMgr.SetShellProfiles(a.settingsMgr.LegacyShellProfiles())
+		return err
+	}
+	a.terminalMgr.SetShellProfiles(profiles)
+	return nil
+}
+
+// GetSSHProfiles 获取所有 SSH 主机配置
+func (a *App) GetSSHProfiles() ([]models.SSHProfile, error) {
+	return a.settingsMgr.GetSSHProfiles()
+}
+
+// SaveSSHProfile 新增或更新 SSH 主机配置，返回带有 ID 的配置
+func (a *App) SaveSSHProfile(profile models.SSHProfile) (models.SSHProfile, error) {
+
+	if err := terminal.ValidateSSHProfile(profile); err != nil {
+		return profile, err
+	}
+	return a.settingsMgr.SaveSSHProfile(profile)
+}
+
+// DeleteSSHProfile 删除 SSH 主机配置
+func (a *App) DeleteSSHProfile(id string) error {
+	return a.settingsMgr.DeleteSSHProfile(id)
+}
+
+// CreateSSHSession 按 SSH 主机配置创建远程终端会话
+func (a *App) CreateSSHSession(profileID string, opts models.SessionOptions) (*models.TerminalSessionInfo, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	profile, err := a.settingsMgr.GetSSHProfile(profileID)
+	if err != nil {
+		return nil, err
+	}
+	return a.terminalMgr.CreateSSHSession(profile, opts)
+}
+
+// ScanSSHHostKey 获取 SSH 主机的密钥指纹，供用户核对
+func (a *App) ScanSSHHostKey(profileID string) (*models.SSHHostKey, error) {
+
+	profile, err := a.settingsMgr.GetSSHProfile(profileID)
+	if err != nil {
+		return nil, err
+	}
+	return terminal.ScanSSHHostKey(profile)
+}
+
+// TrustSSHHostKey 信任用户核对过指纹的 SSH 主机密钥
+func (a *App) TrustSSHHostKey(profileID, fingerprint string) error {
+
+	profile, err := a.settingsMgr.GetSSHProfile(profileID)
+	if err != nil {
+		return err
+	}
+	return terminal.TrustSSHHostKey(profile, fingerprint)
+}
+
+// ListContainers 列出本机 Docker/Podman 中正在运行的容器
+func (a *App) ListContainers() ([]models.Container, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.ListContainers()
+}
+
+// CreateContainerSession 在运行中的容器内打开终端会话
+func (a *App) CreateContainerSession(opts models.ContainerExecOptions) (*models.TerminalSessionInfo, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.CreateContainerSession(opts)
+}
+
+// ListSerialDevices 列出本机可用的串口设备
+func (a *App) ListSerialDevices() ([]models.SerialDevice, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.ListSerialDevices()
+}
+
+// CreateSerialSession 打开串口设备并创建终端会话
+func (a *App) CreateSerialSession(opts models.SerialOptions) (*models.TerminalSessionInfo, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.CreateSerialSession(opts)
+}
+
+// ListTerminalSessions 列出所有终端会话
+func (a *App) ListTerminalSessions() []models.TerminalSessionInfo {
+
+	if a.terminalMgr == nil {
+		return []models.TerminalSessionInfo{}
+	}
+	return a.terminalMgr.ListSessions()
+}
+
+// CloseTerminalSession 关闭终端会话
+func (a *App) CloseTerminalSession(sessionID string) error {
+
+	if a.terminalMgr == nil {
+		return fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.CloseSession(sessionID)
+}
+
+// RenameTerminalSession 重命名终端会话
+func (a *App) RenameTerminalSession(sessionID, title string) error {
+
+	if a.terminalMgr == nil {
+		return fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.RenameSession(sessionID, title)
+}
+
+// SignalTerminalSession 向终端会话的前台进程组或整个会话发送信号
+//
+// target 为 foreground（默认）或 session，signal 可写作 SIGINT、INT 或 int。
+func (a *App) SignalTerminalSession(sessionID, signal, target string) (*models.SignalResult, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.SignalSession(sessionID, signal, target)
+}
+
+// ShareTerminalSession 生成终端会话的只读分享令牌
+func (a *App) ShareTerminalSession(sessionID string) (*models.TerminalShare, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.ShareSession(sessionID)
+}
+
+// ListTerminalShares 列出终端会话的分享令牌
+func (a *App) ListTerminalShares(sessionID string) []models.TerminalShare {
+
+	if a.terminalMgr == nil {
+		return []models.TerminalShare{}
+	}
+	return a.terminalMgr.ListShares(sessionID)
+}
+
+// RevokeTerminalShare 撤销分享令牌
+func (a *App) RevokeTerminalShare(token string) error {
+
+	if a.terminalMgr == nil {
+		return fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.RevokeShare(token)
+}
+
+// ListTerminalViewers 列出终端会话的只读观察者
+func (a *App) ListTerminalViewers(sessionID string) ([]models.TerminalViewer, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.ListViewers(sessionID)
+}
+
+// AddTerminalToBroadcast 将终端会话加入广播输入分组
+func (a *App) AddTerminalToBroadcast(sessionID string) error {
+
+	if a.terminalMgr == nil {
+		return fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.AddToBroadcast(sessionID)
+}
+
+// RemoveTerminalFromBroadcast 将终端会话移出广播输入分组
+func (a *App) RemoveTerminalFromBroadcast(sessionID string) error {
+
+	if a.terminalMgr == nil {
+		return fmt.Errorf("终端未初始化")
+	}
+	a.terminalMgr.RemoveFromBroadcast(sessionID)
+	return nil
+}
+
+// SetTerminalBroadcast 开启或关闭广播输入
+func (a *App) SetTerminalBroadcast(enabled bool) error {
+
+	if a.terminalMgr == nil {
+		return fmt.Errorf("终端未初始化")
+	}
+	a.terminalMgr.SetBroadcast(enabled)
+	return nil
+}
+
+// GetTerminalBroadcastState 获取广播输入分组状态，供前端显示指示
+func (a *App) GetTerminalBroadcastState() models.BroadcastState {
+
+	if a.terminalMgr == nil {
+		return models.BroadcastState{Sessions: []string{}}
+	}
+	return a.terminalMgr.BroadcastState()
+}
+
+// GetTerminalOutput 获取终端会话输出历史中的字节区间
+func (a *App) GetTerminalOutput(sessionID string, from, to int64) (*models.TerminalOutput, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.GetOutputRange(sessionID, from, to)
+}
+
+// GetTerminalLastLines 获取终端会话输出的最后若干行
+func (a *App) GetTerminalLastLines(sessionID string, lines int) (*models.TerminalOutput, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.GetLastLines(sessionID, lines)
+}
+
+// SearchTerminalOutput 在终端会话的输出历史中搜索文本或正则表达式
+func (a *App) SearchTerminalOutput(sessionID string, opts models.ScrollbackSearch) (*models.ScrollbackSearchResult, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.SearchScrollback(sessionID, opts)
+}
+
+// ExportTerminalOutput 将终端会话的输出导出为纯文本、HTML 或原始 ANSI 文件
+//
+// HTML 使用当前主题的终端配色。
+func (a *App) ExportTerminalOutput(sessionID string, opts models.TerminalExport) (*models.TerminalExportResult, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	var theme *models.ThemeInfo
+	a.mu.RLock()
+	if r != nil {
+		theme = r.themeInfo
+	}
+	a.mu.RUnlock()
+	return a.terminalMgr.ExportOutput(sessionID, opts, theme)
+}
+
+// RestartTerminalSession 在同一标签页中重启已退出的 shell
+func (a *App) RestartTerminalSession(sessionID string) error {
+
+	if a.terminalMgr == nil {
+		return fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.RestartSession(sessionID)
+}
+
+// ListTerminalCommands 获取终端会话中通过 shell 集成识别的命令记录
+func (a *App) ListTerminalCommands(sessionID string) ([]models.TerminalCommand, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.ListCommands(sessionID)
+}
+
+// GetTerminalCommandOutput 获取一条命令的输出，index 小于 0 表示最近一条
+func (a *App) GetTerminalCommandOutput(sessionID string, index int) (*models.TerminalOutput, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.GetCommandOutput(sessionID, index)
+}
+
+// GetShellIntegration 获取 shell 集成脚本及安装方式，shell 为空时使用设置中的 shell
+func (a *App) GetShellIntegration(shell string) (*models.ShellIntegration, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.GetShellIntegration(shell)
+}
+
+// SetActiveTerminalSession 通知后端当前显示的终端会话，该会话中的命令结束时不提醒
+func (a *App) SetActiveTerminalSession(sessionID string) error {
+
+	if a.terminalMgr == nil {
+		return fmt.Errorf("终端未初始化")
+	}
+	a.terminalMgr.SetActiveSession(sessionID)
+	return nil
+}
+
+// StartTerminalRecording 开始录制终端会话
+func (a *App) StartTerminalRecording(sessionID string) (string, error) {
+
+	if a.terminalMgr == nil {
+		return "", fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.StartRecording(sessionID)
+}
+
+// StopTerminalRecording 停止录制终端会话
+func (a *App) StopTerminalRecording(sessionID string) (string, error) {
+
+	if a.terminalMgr == nil {
+		return "", fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.StopRecording(sessionID)
+}
+
+// ListTerminalRecordings 列出终端录像
+func (a *App) ListTerminalRecordings() ([]models.RecordingInfo, error) {
+
+	if a.terminalMgr == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.ListRecordings()
+}
+
+// PlayTerminalRecording 在只读终端会话中回放录像
+func (a *App) PlayTerminalRecording(name string, speed float64) (string, error) {
+
+	if a.terminalMgr == nil {
+		return "", fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.PlayRecording(name, speed)
+}
+
+// SetTerminalPlaybackSpeed 调整录像回放速度
+func (a *App) SetTerminalPlaybackSpeed(sessionID string, speed float64) error {
+
+	if a.terminalMgr == nil {
+		return fmt.Errorf("终端未初始化")
+	}
+	return a.terminalMgr.SetPlaybackSpeed(sessionID, speed)
+}
+
 // Shutdown 应用关闭时的清理工作
 func (a *App) shutdown(ctx context.Context) {
 	// 清理单实例锁文件
 	a.cleanupSingleInstanceLock()
 
-	// 关闭终端管理器
+	// 结束所有终端会话并关闭终端管理器
 	if a.terminalMgr != nil {
-		a.terminalMgr.Close()
+		report, err := a.terminalMgr.Shutdown(terminal.ShutdownTimeout)
+		if err != nil {
+			log.Printf("关闭终端服务失败: %v", err)
+		}
+		if len(report.Busy) > 0 {
+			log.Printf("退出时有 %d 个会话仍在运行前台作业", len(report.Busy))
+		}
+		log.Printf("已结束 %d 个终端会话，其中 %d 个被强制终止", report.Sessions, len(report.Killed))
 	}
 	log.Println("应用已关闭")
 }
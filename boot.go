@@ -21,7 +21,9 @@ type Boot struct {
 	lastWindowState *models.WindowState
 
 	// 主题和UI
-	theme        *Theme
+	theme *Theme
+	// 完整的主题配置，包括终端调色板，用于导出终端输出等后端渲染
+	themeInfo    *models.ThemeInfo
 	audioManager *AudioManager
 
 	// 终端管理
@@ -333,11 +335,16 @@ func (r *Boot) loadTheme(themeName string) {
 	if err := json.Unmarshal(data, &theme); err != nil {
 		return
 	}
+	var info models.ThemeInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return
+	}
 
 	r.theme = &theme
+	r.themeInfo = &info
 }
 
-// ChangeTheme 切换主题
+// ChangeTheme 切换主题，r 发布后调用方需持有 App.mu 的写锁
 func (r *Boot) ChangeTheme(themeName string) {
 
 	r.loadTheme(themeName)
@@ -55,8 +55,10 @@ class TerminalManager {
     async createTerminalInstance(terminalIndex, opts = {}) {
         try {
             const parentId = opts.parentId || `terminal${terminalIndex}`;
-            const port = opts.port || 3000;
-            const host = opts.host || "127.0.0.1";
+            // 从后端获取终端服务地址与本次启动的连接令牌
+            const conn = await window.go.main.App.GetTerminalConnection();
+            const port = opts.port || conn.port;
+            const host = opts.host || conn.host;
 
             // 创建Terminal实例
             const terminal = new Terminal({
@@ -97,7 +99,7 @@ class TerminalManager {
             this.cwd[terminalIndex] = "";
 
             // 创建WebSocket连接并附加到终端
-            await this._createWebSocketConnection(terminalIndex, terminal, host, port);
+            await this._createWebSocketConnection(terminalIndex, terminal, host, port, conn.token);
 
             // 设置终端事件监听器
             this._setupTerminalEventListeners(terminalIndex, terminal, parentId);
@@ -141,9 +143,11 @@ class TerminalManager {
     }
 
     // 创建WebSocket连接并使用AttachAddon附加到终端
-    async _createWebSocketConnection(terminalIndex, terminal, host, port) {
+    async _createWebSocketConnection(terminalIndex, terminal, host, port, token) {
         return new Promise((resolve, reject) => {
-            const socket = new WebSocket(`ws://${host}:${port}/webterminal`);
+            // 以标签页序号作为稳定的会话 ID，前端重载后可重新附加到仍在运行的会话
+            const query = `session=tab${terminalIndex}&token=${encodeURIComponent(token)}`;
+            const socket = new WebSocket(`ws://${host}:${port}/webterminal?${query}`);
             socket.binaryType = 'arraybuffer';
 
             socket.onopen = () => {
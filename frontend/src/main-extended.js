@@ -359,16 +359,6 @@ window.openSettings = async () => {
                         <th>Description</th>
                         <th>Value</th>
                     </tr>
-                    <tr>
-                        <td>shell</td>
-                        <td>终端模拟器程序</td>
-                        <td><input type="text" id="settingsEditor-shell" value="${settings.Shell || ''}"></td>
-                    </tr>
-                    <tr>
-                        <td>shellArgs</td>
-                        <td>传递给 shell 的参数</td>
-                        <td><input type="text" id="settingsEditor-shellArgs" value="${settings.ShellArgs || ''}"></td>
-                    </tr>
                     <tr>
                         <td>cwd</td>
                         <td>启动时的工作目录</td>
@@ -561,8 +551,6 @@ window.writeSettingsFile = async () => {
     try {
         // 收集设置数据
         const settingsData = {
-            shell: document.getElementById("settingsEditor-shell").value,
-            shellArgs: document.getElementById("settingsEditor-shellArgs").value,
             cwd: document.getElementById("settingsEditor-cwd").value,
             env: document.getElementById("settingsEditor-env").value,
             username: document.getElementById("settingsEditor-username").value,
@@ -4,28 +4,37 @@ import "time"
 
 // Settings 配置结构体
 type Settings struct {
-	Shell                     string  `json:"shell"`
-	ShellArgs                 string  `json:"shellArgs"`
-	Cwd                       string  `json:"cwd"`
-	Keyboard                  string  `json:"keyboard"`
-	Theme                     string  `json:"theme"`
-	TermFontSize              int     `json:"termFontSize"`
-	Audio                     bool    `json:"audio"`
-	AudioVolume               float64 `json:"audioVolume"`
-	DisableFeedbackAudio      bool    `json:"disableFeedbackAudio"`
-	ClockHours                int     `json:"clockHours"`
-	PingAddr                  string  `json:"pingAddr"`
-	Port                      int     `json:"port"`
-	Nointro                   bool    `json:"nointro"`
-	Nocursor                  bool    `json:"nocursor"`
-	ForceFullscreen           bool    `json:"forceFullscreen"`
-	AllowWindowed             bool    `json:"allowWindowed"`
-	ExcludeThreadsFromToplist bool    `json:"excludeThreadsFromToplist"`
-	HideDotfiles              bool    `json:"hideDotfiles"`
-	FsListView                bool    `json:"fsListView"`
-	ExperimentalGlobeFeatures bool    `json:"experimentalGlobeFeatures"`
-	ExperimentalFeatures      bool    `json:"experimentalFeatures"`
-	DisableAutoUpdate         bool    `json:"disableAutoUpdate"`
+	Shell                     string   `json:"shell"`     // 已由 shell 配置取代，仅用于迁移旧设置
+	ShellArgs                 string   `json:"shellArgs"` // 已由 shell 配置取代，仅用于迁移旧设置
+	Cwd                       string   `json:"cwd"`
+	Keyboard                  string   `json:"keyboard"`
+	Theme                     string   `json:"theme"`
+	TermFontSize              int      `json:"termFontSize"`
+	Audio                     bool     `json:"audio"`
+	AudioVolume               float64  `json:"audioVolume"`
+	DisableFeedbackAudio      bool     `json:"disableFeedbackAudio"`
+	ClockHours                int      `json:"clockHours"`
+	PingAddr                  string   `json:"pingAddr"`
+	Port                      int      `json:"port"`
+	Nointro                   bool     `json:"nointro"`
+	Nocursor                  bool     `json:"nocursor"`
+	ForceFullscreen           bool     `json:"forceFullscreen"`
+	AllowWindowed             bool     `json:"allowWindowed"`
+	ExcludeThreadsFromToplist bool     `json:"excludeThreadsFromToplist"`
+	HideDotfiles              bool     `json:"hideDotfiles"`
+	FsListView                bool     `json:"fsListView"`
+	ExperimentalGlobeFeatures bool     `json:"experimentalGlobeFeatures"`
+	ExperimentalFeatures      bool     `json:"experimentalFeatures"`
+	DisableAutoUpdate         bool     `json:"disableAutoUpdate"`
+	ScrollbackSize            int      `json:"scrollbackSize"`         // 每个终端会话保留的输出字节数（4 KiB 到 4 MiB）
+	AllowRemoteTerminal       bool     `json:"allowRemoteTerminal"`    // 允许其他主机连接终端 WebSocket 服务
+	TerminalAllowedOrigins    []string `json:"terminalAllowedOrigins"` // 额外允许连接终端的 Origin
+	TerminalCompression       bool     `json:"terminalCompression"`    // 终端 WebSocket 启用 permessage-deflate 压缩
+	NotifyLongCommands        bool     `json:"notifyLongCommands"`     // 后台标签页中长时间命令结束时提醒
+	LongCommandThreshold      int      `json:"longCommandThreshold"`   // 需要提醒的命令最短运行时间（秒），0 为默认 10 秒
+	LongCommandSound          bool     `json:"longCommandSound"`       // 提醒时播放提示音
+	ContainerSocket           string   `json:"containerSocket"`        // Docker/Podman API 的 unix socket，为空时自动探测
+	MaxSessions               int      `json:"maxSessions"`            // 同时存在的终端会话上限，0 为不限制
 	Env                       string
 	Username                  string
 	Monitor                   int
@@ -105,6 +114,125 @@ type Shortcut struct {
 	Linebreak bool   `json:"linebreak,omitempty"`
 }
 
+// OutputTrigger 终端输出触发规则结构体
+type OutputTrigger struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Pattern  string `json:"pattern"`            // 正则表达式，匹配去除转义序列后的单行输出
+	Action   string `json:"action"`             // highlight、notify、sound 或 respond
+	Color    string `json:"color,omitempty"`    // highlight 使用的颜色
+	Sound    string `json:"sound,omitempty"`    // sound 播放的提示音，默认 info
+	Response string `json:"response,omitempty"` // respond 写入终端的内容
+	Cooldown int    `json:"cooldown,omitempty"` // 同一规则在同一会话中两次触发的最短间隔（毫秒），默认 1000
+	Enabled  bool   `json:"enabled"`
+}
+
+// TriggerMatch 输出触发规则命中事件结构体
+type TriggerMatch struct {
+	SessionID string `json:"sessionId"`
+	TriggerID string `json:"triggerId"`
+	Name      string `json:"name"`
+	Action    string `json:"action"`
+	Line      string `json:"line"`   // 命中的行（已去除转义序列）
+	Match     string `json:"match"`  // 命中的文本
+	Offset    int64  `json:"offset"` // 该行在会话输出中的起始偏移
+	Color     string `json:"color,omitempty"`
+	Sound     string `json:"sound,omitempty"`
+}
+
+// ShellProfile 命名 shell 配置结构体
+type ShellProfile struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Command string            `json:"command"`        // 可执行文件名或路径，按 PATH 查找
+	Args    string            `json:"args,omitempty"` // 以空白分隔的参数
+	Cwd     string            `json:"cwd,omitempty"`  // 为空时使用设置中的工作目录
+	Env     map[string]string `json:"env,omitempty"`  // 叠加在终端环境变量之上
+	Icon    string            `json:"icon,omitempty"`
+	Login   bool              `json:"login"` // 以登录 shell 启动（类 Unix 系统）
+	Limits  ShellLimits       `json:"limits"`
+	// 无输入输出超过该秒数后结束会话，0 为不限制
+	IdleTimeout int `json:"idleTimeout,omitempty"`
+	// 以下字段在加载时填充，不保存
+	Path  string `json:"path,omitempty"`  // 解析后的可执行文件路径
+	Error string `json:"error,omitempty"` // 配置无效的原因
+}
+
+// ShellLimits shell 进程资源限制结构体，仅在 Linux 上生效，0 为不限制
+//
+// 优先使用 cgroup v2，需要程序所在（或其父）cgroup 已向子 cgroup 启用相应控制器，
+// 例如由 systemd 以 Delegate= 委派；否则只能以 RLIMIT_AS 限制内存（按虚拟内存计算），
+// CPU 与进程数限制不生效。原因显示在会话信息的 limitsNote 中。
+type ShellLimits struct {
+	MemoryMB     int `json:"memoryMB,omitempty"`     // 内存上限（MiB）
+	CPUPercent   int `json:"cpuPercent,omitempty"`   // CPU 配额，100 为一个核心
+	MaxProcesses int `json:"maxProcesses,omitempty"` // 进程数上限
+}
+
+// ShellProfiles shell 配置列表结构体
+type ShellProfiles struct {
+	Default  string         `json:"default"` // 默认配置的 ID
+	Profiles []ShellProfile `json:"profiles"`
+}
+
+// SSHProfile SSH 主机配置结构体
+type SSHProfile struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Host      string `json:"host"`
+	Port      int    `json:"port,omitempty"` // 默认 22
+	User      string `json:"user"`
+	KeyFile   string `json:"keyFile,omitempty"` // 私钥文件，不支持带口令的私钥（请使用 ssh-agent）
+	UseAgent  bool   `json:"useAgent"`
+	KeepAlive int    `json:"keepAlive,omitempty"` // 保活间隔（秒），0 为默认 30 秒，负数关闭
+	Reconnect bool   `json:"reconnect"`           // 连接中断后自动重连
+}
+
+// SSHHostKey SSH 主机密钥信息结构体
+type SSHHostKey struct {
+	Host        string `json:"host"`
+	Type        string `json:"type"`
+	Fingerprint string `json:"fingerprint"` // SHA256 指纹
+	Known       bool   `json:"known"`       // 是否已在 known_hosts 中
+}
+
+// Container 容器信息结构体
+type Container struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Image  string `json:"image"`
+	State  string `json:"state"`  // running、paused 等
+	Status string `json:"status"` // 例如 Up 5 minutes
+}
+
+// ContainerExecOptions 容器终端会话参数结构体
+type ContainerExecOptions struct {
+	Title     string `json:"title"`
+	Container string `json:"container"`         // 容器 ID 或名称
+	Command   string `json:"command,omitempty"` // 为空时优先使用 bash，不存在时使用 sh
+	User      string `json:"user,omitempty"`
+	Cwd       string `json:"cwd,omitempty"`
+}
+
+// SerialOptions 串口会话参数结构体
+type SerialOptions struct {
+	Title       string `json:"title"`
+	Device      string `json:"device"`                // 设备路径，例如 /dev/ttyUSB0
+	Baud        int    `json:"baud,omitempty"`        // 波特率，默认 115200
+	DataBits    int    `json:"dataBits,omitempty"`    // 数据位 5-8，默认 8
+	Parity      string `json:"parity,omitempty"`      // none、odd、even、mark、space，默认 none
+	StopBits    int    `json:"stopBits,omitempty"`    // 1 或 2，默认 1
+	FlowControl string `json:"flowControl,omitempty"` // none、rtscts、xonxoff，默认 none
+}
+
+// SerialDevice 可用串口设备信息结构体
+type SerialDevice struct {
+	Path        string `json:"path"`
+	Name        string `json:"name"`
+	Driver      string `json:"driver,omitempty"`
+	Description string `json:"description,omitempty"` // USB 设备的厂商与产品名
+}
+
 // WindowState 窗口状态结构体
 type WindowState struct {
 	UseFullscreen bool `json:"useFullscreen"`
@@ -121,9 +249,219 @@ type Terminal struct {
 	Role   string
 	Shell  string
 	Params string
+	Login  bool // 以登录 shell 启动
+	Limits ShellLimits
 	Cwd    string
 	Env    map[string]string
 	Port   int
+	// 空闲超时（秒），0 为不限制
+	IdleTimeout int
+}
+
+// TerminalConnection 终端 WebSocket 连接信息结构体
+type TerminalConnection struct {
+	Host  string `json:"host"`
+	Port  int    `json:"port"`
+	Path  string `json:"path"`
+	Token string `json:"token"` // 每次启动随机生成，连接时作为查询参数 token 传递
+}
+
+// TerminalShare 终端只读分享信息结构体
+type TerminalShare struct {
+	SessionID string `json:"sessionId"`
+	Token     string `json:"token"`
+	URL       string `json:"url"`       // 观察者连接地址
+	LocalOnly bool   `json:"localOnly"` // 服务器只监听回环地址，链接仅本机可用
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// TerminalViewer 以只读方式附加到会话的观察者结构体
+type TerminalViewer struct {
+	ID          string `json:"id"`
+	RemoteAddr  string `json:"remoteAddr"`
+	ConnectedAt int64  `json:"connectedAt"`
+}
+
+// BroadcastState 广播输入分组状态结构体
+type BroadcastState struct {
+	Enabled  bool     `json:"enabled"`
+	Sessions []string `json:"sessions"` // 分组内的会话 ID
+}
+
+// SessionOptions 新建终端会话的选项结构体
+type SessionOptions struct {
+	Title   string `json:"title"`
+	Profile string `json:"profile"` // shell 配置 ID，为空时使用默认配置
+	Shell   string `json:"shell"`   // 临时指定的 shell，优先于 Profile
+	Args    string `json:"args"`    // 仅在指定 Shell 时生效
+	Cwd     string `json:"cwd"`     // 为空时使用设置中的工作目录
+}
+
+// TerminalSessionInfo 终端会话信息结构体
+type TerminalSessionInfo struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Kind      string `json:"kind"`              // local、playback 等
+	Profile   string `json:"profile,omitempty"` // 本地会话使用的 shell 配置 ID
+	Shell     string `json:"shell"`
+	Cwd       string `json:"cwd"`
+	CreatedAt int64  `json:"createdAt"`
+	Clients   int    `json:"clients"` // 当前附加的连接数
+	Running   bool   `json:"running"`
+	ExitCode  *int   `json:"exitCode,omitempty"`
+	Recording bool   `json:"recording"`
+	Process   string `json:"process"` // 前台进程描述，例如 vim、ssh prod-1
+	Busy      bool   `json:"busy"`    // 前台是否有 shell 之外的作业在运行
+	// 是否收到过 OSC 133 标记，即 shell 集成已启用
+	ShellIntegration bool `json:"shellIntegration"`
+	// 资源限制的实现方式：cgroup 或 rlimit，未设置或未生效时为空
+	Limits     string `json:"limits,omitempty"`
+	LimitsNote string `json:"limitsNote,omitempty"` // 未能完整应用资源限制的原因
+}
+
+// ShutdownReport 程序退出时结束终端会话的结果
+type ShutdownReport struct {
+	Sessions int                   `json:"sessions"` // 结束的会话数量
+	Busy     []TerminalSessionInfo `json:"busy"`     // 退出时仍有前台作业在运行的会话
+	Killed   []string              `json:"killed"`   // 收到 SIGHUP 后超时仍有进程存活、被强制终止的会话 ID
+}
+
+// SignalResult 向终端会话发送信号的结果
+type SignalResult struct {
+	SessionID string `json:"sessionId"`
+	Signal    string `json:"signal"`          // 规范化后的信号名，例如 SIGINT
+	Target    string `json:"target"`          // foreground 或 session
+	Method    string `json:"method"`          // kill、tty 或 remote
+	Pgids     []int  `json:"pgids,omitempty"` // 收到信号的本地进程组
+}
+
+// TerminalCwdChange 终端工作目录变化事件结构体
+type TerminalCwdChange struct {
+	SessionID string `json:"sessionId"`
+	Cwd       string `json:"cwd"`
+	Source    string `json:"source"` // proc 或 osc7
+}
+
+// TerminalProcessChange 终端前台进程变化事件结构体
+type TerminalProcessChange struct {
+	SessionID string `json:"sessionId"`
+	Pid       int    `json:"pid"`
+	Name      string `json:"name"`
+	Title     string `json:"title"`
+	Cmdline   string `json:"cmdline"`
+	Busy      bool   `json:"busy"` // 前台进程不是 shell 本身
+}
+
+// TerminalOutput 终端输出片段结构体
+type TerminalOutput struct {
+	SessionID string `json:"sessionId"`
+	Start     int64  `json:"start"` // 片段起始偏移（自会话开始的字节数）
+	End       int64  `json:"end"`   // 片段结束偏移
+	First     int64  `json:"first"` // 当前仍保留的最早偏移
+	Data      string `json:"data"`
+}
+
+// ScrollbackSearch 搜索终端输出历史的参数
+//
+// 匹配在去除转义序列后的文本上进行，偏移仍是原始输出中的字节位置。
+type ScrollbackSearch struct {
+	Query         string `json:"query"`
+	Regex         bool   `json:"regex"`         // 按正则表达式（RE2 语法）匹配，否则按普通文本匹配
+	CaseSensitive bool   `json:"caseSensitive"` // 区分大小写，默认不区分
+	From          int64  `json:"from"`          // 搜索区间的起始偏移，早于保留窗口时从窗口开头搜索
+	To            int64  `json:"to"`            // 搜索区间的结束偏移，小于等于 0 表示直到末尾
+	MaxResults    int    `json:"maxResults"`    // 最多返回的匹配数，0 使用默认值
+	Context       int    `json:"context"`       // 每处匹配前后附带的行数
+}
+
+// ScrollbackMatch 输出历史中的一处匹配
+type ScrollbackMatch struct {
+	Start  int64    `json:"start"`            // 匹配在原始输出中的起始偏移
+	End    int64    `json:"end"`              // 匹配在原始输出中的结束偏移
+	Text   string   `json:"text"`             // 匹配的文本
+	Line   string   `json:"line"`             // 匹配所在的行，跨行匹配时包含所有相关行
+	Column int      `json:"column"`           // 匹配在行内的起始字符位置
+	Before []string `json:"before,omitempty"` // 匹配之前的上下文行
+	After  []string `json:"after,omitempty"`  // 匹配之后的上下文行
+}
+
+// ScrollbackSearchResult 输出历史的搜索结果
+type ScrollbackSearchResult struct {
+	SessionID string            `json:"sessionId"`
+	First     int64             `json:"first"`     // 当前仍保留的最早偏移
+	End       int64             `json:"end"`       // 搜索时输出的结束偏移
+	Matches   []ScrollbackMatch `json:"matches"`   // 按偏移排列的匹配
+	Truncated bool              `json:"truncated"` // 匹配数超过上限，之后的匹配未返回
+}
+
+// TerminalExport 导出终端输出的参数
+type TerminalExport struct {
+	Path   string `json:"path"`   // 导出文件的路径
+	Format string `json:"format"` // text、html 或 ansi，为空时按扩展名判断
+	From   int64  `json:"from"`   // 导出区间的起始偏移，早于保留窗口时从窗口开头导出
+	To     int64  `json:"to"`     // 导出区间的结束偏移，小于等于 0 表示直到末尾
+}
+
+// TerminalExportResult 导出终端输出的结果
+type TerminalExportResult struct {
+	SessionID string `json:"sessionId"`
+	Path      string `json:"path"`
+	Format    string `json:"format"`
+	Start     int64  `json:"start"` // 实际导出的起始偏移
+	End       int64  `json:"end"`   // 实际导出的结束偏移
+	Size      int    `json:"size"`  // 写入文件的字节数
+}
+
+// TerminalCommand 通过 shell 集成（OSC 133）识别的一条命令
+//
+// 偏移均为自会话开始的输出字节数，可用于 GetTerminalOutput 取出对应片段。
+type TerminalCommand struct {
+	SessionID    string `json:"sessionId"`
+	Index        int    `json:"index"` // 会话内递增的命令序号
+	Command      string `json:"command"`
+	Cwd          string `json:"cwd"`
+	PromptStart  int64  `json:"promptStart"`  // 提示符开始
+	CommandStart int64  `json:"commandStart"` // 命令输入开始，未知时为 -1
+	OutputStart  int64  `json:"outputStart"`  // 命令输出开始
+	OutputEnd    int64  `json:"outputEnd"`    // 命令输出结束，运行中为 -1
+	StartedAt    int64  `json:"startedAt"`    // 毫秒时间戳
+	FinishedAt   int64  `json:"finishedAt"`   // 毫秒时间戳，运行中为 0
+	Duration     int64  `json:"duration"`     // 毫秒
+	ExitCode     *int   `json:"exitCode,omitempty"`
+	Running      bool   `json:"running"`
+}
+
+// ShellIntegration shell 集成脚本信息结构体
+type ShellIntegration struct {
+	Shell   string `json:"shell"`   // bash、zsh 或 fish
+	Path    string `json:"path"`    // 脚本在磁盘上的位置
+	RCFile  string `json:"rcFile"`  // 需要修改的配置文件
+	Snippet string `json:"snippet"` // 加入配置文件的一行
+	Script  string `json:"script"`
+}
+
+// CommandCompletion 长时间命令结束提醒结构体
+type CommandCompletion struct {
+	SessionID string `json:"sessionId"`
+	Title     string `json:"title"`             // 会话标题
+	Command   string `json:"command,omitempty"` // 命令行，无法获取时为空
+	Process   string `json:"process,omitempty"` // 前台进程描述
+	Duration  int64  `json:"duration"`          // 毫秒
+	ExitCode  *int   `json:"exitCode,omitempty"`
+	Source    string `json:"source"`          // shell-integration、process 或 idle
+	Sound     string `json:"sound,omitempty"` // 需要播放的提示音，例如 info、error
+}
+
+// RecordingInfo 终端录像信息结构体
+type RecordingInfo struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	ModTime   int64  `json:"modTime"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Title     string `json:"title"`
+	Timestamp int64  `json:"timestamp"` // 录制开始时间（Unix 秒）
 }
 
 // MemoryInfo 内存信息结构体
@@ -24,7 +24,7 @@ func (m *Manager) SetupDirectories() error {
 	}
 
 	// 创建子目录
-	dirs := []string{"themes", "keyboards", "fonts"}
+	dirs := []string{"themes", "keyboards", "fonts", "recordings"}
 	for _, dir := range dirs {
 		dirPath := filepath.Join(AppDataDir, dir)
 		if err := os.MkdirAll(dirPath, 0755); err != nil {
@@ -99,6 +99,8 @@ func (m *Manager) createDefaultSettings(settingsFile string) error {
 		ExperimentalGlobeFeatures: false,
 		ExperimentalFeatures:      false,
 		DisableAutoUpdate:         false,
+		ScrollbackSize:            1024 * 1024,
+		LongCommandThreshold:      10,
 	}
 
 	data, err := json.MarshalIndent(settings, "", "    ")
@@ -0,0 +1,172 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"edex-ui-golang/internal/models"
+	"edex-ui-golang/internal/utils"
+)
+
+// shellProfilesFileName shell 配置文件
+const shellProfilesFileName = "shell_profiles.json"
+
+// migratedShellProfileID 由旧版 Shell/ShellArgs 设置迁移而来的配置 ID
+const migratedShellProfileID = "default"
+
+// GetShellProfiles 获取所有 shell 配置
+//
+// 配置文件不存在时由旧版设置中的 Shell 与 ShellArgs 生成一个默认配置并保存。
+func (m *Manager) GetShellProfiles() (models.ShellProfiles, error) {
+
+	AppDataDir, err := utils.GetAppDir()
+	if err != nil {
+		return models.ShellProfiles{}, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(AppDataDir, shellProfilesFileName))
+	if os.IsNotExist(err) {
+		profiles := m.LegacyShellProfiles()
+		if err := m.saveShellProfiles(profiles); err != nil {
+			return profiles, err
+		}
+		log.Printf("已从旧版 shell 设置生成 shell 配置: %s %s", profiles.Profiles[0].Command, profiles.Profiles[0].Args)
+		return profiles, nil
+	}
+	if err != nil {
+		return models.ShellProfiles{}, err
+	}
+
+	var profiles models.ShellProfiles
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return models.ShellProfiles{}, fmt.Errorf("解析 %s 失败: %v", shellProfilesFileName, err)
+	}
+	if profiles.Profiles == nil {
+		profiles.Profiles = []models.ShellProfile{}
+	}
+	return profiles, nil
+}
+
+// LegacyShellProfiles 由旧版设置中的 Shell 与 ShellArgs 生成 shell 配置
+//
+// 只在配置文件不存在（首次迁移）或无法读取时使用，之后 shell 配置是唯一的来源。
+func (m *Manager) LegacyShellProfiles() models.ShellProfiles {
+
+	command, args := "", ""
+	if m.settings != nil {
+		command, args = strings.TrimSpace(m.settings.Shell), m.settings.ShellArgs
+	}
+	if command == "" {
+		if runtime.GOOS == "windows" {
+			command = "powershell.exe"
+		} else {
+			command = "bash"
+		}
+	}
+
+	name := strings.TrimSuffix(filepath.Base(command), filepath.Ext(command))
+	return models.ShellProfiles{
+		Default: migratedShellProfileID,
+		Profiles: []models.ShellProfile{
+			{ID: migratedShellProfileID, Name: name, Command: command, Args: args},
+		},
+	}
+}
+
+// SaveShellProfile 保存 shell 配置，ID 为空时新增并生成 ID，否则更新同 ID 的配置
+func (m *Manager) SaveShellProfile(profile models.ShellProfile) (models.ShellProfile, error) {
+
+	profiles, err := m.GetShellProfiles()
+	if err != nil {
+		return profile, err
+	}
+
+	if profile.ID == "" {
+		profile.ID = newRecordID()
+		profiles.Profiles = append(profiles.Profiles, profile)
+		if profiles.Default == "" {
+			profiles.Default = profile.ID
+		}
+		return profile, m.saveShellProfiles(profiles)
+	}
+
+	for i, p := range profiles.Profiles {
+		if p.ID == profile.ID {
+			profiles.Profiles[i] = profile
+			return profile, m.saveShellProfiles(profiles)
+		}
+	}
+	return profile, fmt.Errorf("shell 配置不存在: %s", profile.ID)
+}
+
+// DeleteShellProfile 删除 shell 配置，删除默认配置时第一个剩余配置成为默认配置
+func (m *Manager) DeleteShellProfile(id string) error {
+
+	profiles, err := m.GetShellProfiles()
+	if err != nil {
+		return err
+	}
+
+	newProfiles := []models.ShellProfile{}
+	for _, p := range profiles.Profiles {
+		if p.ID != id {
+			newProfiles = append(newProfiles, p)
+		}
+	}
+	if len(newProfiles) == len(profiles.Profiles) {
+		return fmt.Errorf("shell 配置不存在: %s", id)
+	}
+	if len(newProfiles) == 0 {
+		return fmt.Errorf("至少需要保留一个 shell 配置")
+	}
+
+	profiles.Profiles = newProfiles
+	if profiles.Default == id {
+		profiles.Default = newProfiles[0].ID
+	}
+	return m.saveShellProfiles(profiles)
+}
+
+// SetDefaultShellProfile 设置默认 shell 配置
+func (m *Manager) SetDefaultShellProfile(id string) error {
+
+	profiles, err := m.GetShellProfiles()
+	if err != nil {
+		return err
+	}
+	for _, p := range profiles.Profiles {
+		if p.ID == id {
+			profiles.Default = id
+			return m.saveShellProfiles(profiles)
+		}
+	}
+	return fmt.Errorf("shell 配置不存在: %s", id)
+}
+
+// saveShellProfiles 保存 shell 配置到文件，加载时填充的字段不保存
+func (m *Manager) saveShellProfiles(profiles models.ShellProfiles) error {
+
+	AppDataDir, err := utils.GetAppDir()
+	if err != nil {
+		return err
+	}
+
+	stored := profiles
+	stored.Profiles = make([]models.ShellProfile, len(profiles.Profiles))
+	for i, p := range profiles.Profiles {
+		p.Path, p.Error = "", ""
+		stored.Profiles[i] = p
+	}
+
+	data, err := json.MarshalIndent(stored, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return utils.SafeWriteFile(filepath.Join(AppDataDir, shellProfilesFileName), data, 0644)
+}
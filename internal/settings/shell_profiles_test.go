@@ -0,0 +1,70 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"edex-ui-golang/internal/models"
+	"edex-ui-golang/internal/utils"
+)
+
+func TestLegacyShellProfiles(t *testing.T) {
+
+	fallback, fallbackName := "bash", "bash"
+	if runtime.GOOS == "windows" {
+		fallback, fallbackName = "powershell.exe", "powershell"
+	}
+
+	tests := []struct {
+		name     string
+		settings *models.Settings
+		want     models.ShellProfile
+	}{
+		{"legacy shell", &models.Settings{Shell: "/usr/bin/zsh", ShellArgs: "-l"}, models.ShellProfile{Name: "zsh", Command: "/usr/bin/zsh", Args: "-l"}},
+		{"extension trimmed from name", &models.Settings{Shell: "pwsh.exe"}, models.ShellProfile{Name: "pwsh", Command: "pwsh.exe"}},
+		{"blank shell", &models.Settings{Shell: "  ", ShellArgs: "-i"}, models.ShellProfile{Name: fallbackName, Command: fallback, Args: "-i"}},
+		{"settings not loaded", nil, models.ShellProfile{Name: fallbackName, Command: fallback}},
+	}
+	for _, tt := range tests {
+		profiles := (&Manager{settings: tt.settings}).LegacyShellProfiles()
+		tt.want.ID = migratedShellProfileID
+		if profiles.Default != migratedShellProfileID || !reflect.DeepEqual(profiles.Profiles, []models.ShellProfile{tt.want}) {
+			t.Errorf("%s: profiles = %+v, want default %+v", tt.name, profiles, tt.want)
+		}
+	}
+}
+
+func TestGetShellProfilesMigratesOnce(t *testing.T) {
+
+	dir, err := utils.GetAppDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, shellProfilesFileName)
+	if _, err := os.Stat(file); err == nil {
+		t.Skipf("%s already exists", file)
+	}
+	t.Cleanup(func() { os.Remove(file) })
+
+	m := &Manager{settings: &models.Settings{Shell: "fish", ShellArgs: "--login"}}
+	profiles, err := m.GetShellProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := profiles.Profiles[0]; got.Command != "fish" || got.Args != "--login" || profiles.Default != got.ID {
+		t.Fatalf("migrated profiles = %+v", profiles)
+	}
+
+	// 迁移后 shell 配置是唯一的来源，旧版设置的修改不再生效
+	m.settings.Shell = "zsh"
+	profiles, err = m.GetShellProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := profiles.Profiles[0].Command; got != "fish" {
+		t.Errorf("command after legacy setting changed = %q, want fish", got)
+	}
+}
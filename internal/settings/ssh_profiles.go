@@ -0,0 +1,112 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"edex-ui-golang/internal/models"
+	"edex-ui-golang/internal/utils"
+)
+
+// sshProfilesFileName SSH 主机配置文件
+const sshProfilesFileName = "ssh_profiles.json"
+
+// GetSSHProfiles 获取所有 SSH 主机配置，文件不存在时返回空列表
+func (m *Manager) GetSSHProfiles() ([]models.SSHProfile, error) {
+
+	AppDataDir, err := utils.GetAppDir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(AppDataDir, sshProfilesFileName))
+	if os.IsNotExist(err) {
+		return []models.SSHProfile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := []models.SSHProfile{}
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %v", sshProfilesFileName, err)
+	}
+	return profiles, nil
+}
+
+// GetSSHProfile 按 ID 获取 SSH 主机配置
+func (m *Manager) GetSSHProfile(id string) (models.SSHProfile, error) {
+
+	profiles, err := m.GetSSHProfiles()
+	if err != nil {
+		return models.SSHProfile{}, err
+	}
+	for _, p := range profiles {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return models.SSHProfile{}, fmt.Errorf("SSH 主机配置不存在: %s", id)
+}
+
+// SaveSSHProfile 保存 SSH 主机配置，ID 为空时新增并生成 ID，否则更新同 ID 的配置
+func (m *Manager) SaveSSHProfile(profile models.SSHProfile) (models.SSHProfile, error) {
+
+	profiles, err := m.GetSSHProfiles()
+	if err != nil {
+		return profile, err
+	}
+
+	if profile.ID == "" {
+		profile.ID = newRecordID()
+		profiles = append(profiles, profile)
+		return profile, m.saveSSHProfiles(profiles)
+	}
+
+	for i, p := range profiles {
+		if p.ID == profile.ID {
+			profiles[i] = profile
+			return profile, m.saveSSHProfiles(profiles)
+		}
+	}
+	return profile, fmt.Errorf("SSH 主机配置不存在: %s", profile.ID)
+}
+
+// DeleteSSHProfile 删除 SSH 主机配置
+func (m *Manager) DeleteSSHProfile(id string) error {
+
+	profiles, err := m.GetSSHProfiles()
+	if err != nil {
+		return err
+	}
+
+	newProfiles := []models.SSHProfile{}
+	for _, p := range profiles {
+		if p.ID != id {
+			newProfiles = append(newProfiles, p)
+		}
+	}
+	if len(newProfiles) == len(profiles) {
+		return fmt.Errorf("SSH 主机配置不存在: %s", id)
+	}
+
+	return m.saveSSHProfiles(newProfiles)
+}
+
+// saveSSHProfiles 保存 SSH 主机配置到文件
+func (m *Manager) saveSSHProfiles(profiles []models.SSHProfile) error {
+
+	AppDataDir, err := utils.GetAppDir()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(profiles, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return utils.SafeWriteFile(filepath.Join(AppDataDir, sshProfilesFileName), data, 0644)
+}
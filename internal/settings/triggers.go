@@ -0,0 +1,124 @@
+package settings
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"edex-ui-golang/internal/models"
+	"edex-ui-golang/internal/utils"
+)
+
+// triggersFileName 输出触发规则文件，与 shortcuts.json 位于同一目录
+const triggersFileName = "triggers.json"
+
+// GetTriggers 获取所有输出触发规则，文件不存在时返回空列表
+func (m *Manager) GetTriggers() ([]models.OutputTrigger, error) {
+
+	AppDataDir, err := utils.GetAppDir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(AppDataDir, triggersFileName))
+	if os.IsNotExist(err) {
+		return []models.OutputTrigger{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	triggers := []models.OutputTrigger{}
+	if err := json.Unmarshal(data, &triggers); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %v", triggersFileName, err)
+	}
+	return triggers, nil
+}
+
+// AddTrigger 添加输出触发规则，未指定 ID 时自动生成
+func (m *Manager) AddTrigger(trigger models.OutputTrigger) (models.OutputTrigger, error) {
+
+	triggers, err := m.GetTriggers()
+	if err != nil {
+		return trigger, err
+	}
+
+	if trigger.ID == "" {
+		trigger.ID = newRecordID()
+	}
+	for _, t := range triggers {
+		if t.ID == trigger.ID {
+			return trigger, fmt.Errorf("触发规则 %s 已存在", trigger.ID)
+		}
+	}
+
+	triggers = append(triggers, trigger)
+	return trigger, m.saveTriggers(triggers)
+}
+
+// UpdateTrigger 更新输出触发规则
+func (m *Manager) UpdateTrigger(trigger models.OutputTrigger) error {
+
+	triggers, err := m.GetTriggers()
+	if err != nil {
+		return err
+	}
+
+	for i, t := range triggers {
+		if t.ID == trigger.ID {
+			triggers[i] = trigger
+			return m.saveTriggers(triggers)
+		}
+	}
+	return fmt.Errorf("触发规则不存在: %s", trigger.ID)
+}
+
+// DeleteTrigger 删除输出触发规则
+func (m *Manager) DeleteTrigger(id string) error {
+
+	triggers, err := m.GetTriggers()
+	if err != nil {
+		return err
+	}
+
+	newTriggers := []models.OutputTrigger{}
+	for _, t := range triggers {
+		if t.ID != id {
+			newTriggers = append(newTriggers, t)
+		}
+	}
+	if len(newTriggers) == len(triggers) {
+		return fmt.Errorf("触发规则不存在: %s", id)
+	}
+
+	return m.saveTriggers(newTriggers)
+}
+
+// saveTriggers 保存输出触发规则到文件
+func (m *Manager) saveTriggers(triggers []models.OutputTrigger) error {
+
+	AppDataDir, err := utils.GetAppDir()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(triggers, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return utils.SafeWriteFile(filepath.Join(AppDataDir, triggersFileName), data, 0644)
+}
+
+// newRecordID 生成触发规则、SSH 主机配置等记录的 ID
+func newRecordID() string {
+
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("t%p", &buf)
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,83 @@
+package terminal
+
+import "unicode/utf8"
+
+// stripANSI 去除输出中的转义序列与控制字符，得到可读文本
+//
+// 处理 CSI、OSC、DCS 等序列；退格会删除前一个字符，回车被丢弃，换行与制表符保留。
+func stripANSI(p []byte) []byte {
+
+	out, _ := stripANSIMapped(p, false)
+	return out
+}
+
+// stripANSIMapped 与 stripANSI 相同，mapped 为 true 时同时返回每个输出字节在 p 中的位置
+func stripANSIMapped(p []byte, mapped bool) ([]byte, []int) {
+
+	out := make([]byte, 0, len(p))
+	var offsets []int
+	if mapped {
+		offsets = make([]int, 0, len(p))
+	}
+	for i := 0; i < len(p); i++ {
+		b := p[i]
+		switch {
+		case b == 0x1b:
+			i = skipEscape(p, i)
+		case b == '\b':
+			if len(out) > 0 {
+				_, size := utf8.DecodeLastRune(out)
+				out = out[:len(out)-size]
+				if mapped {
+					offsets = offsets[:len(offsets)-size]
+				}
+			}
+		case b == '\n' || b == '\t' || (b >= 0x20 && b != 0x7f):
+			out = append(out, b)
+			if mapped {
+				offsets = append(offsets, i)
+			}
+		default:
+			// 其余控制字符（含回车）不可见
+		}
+	}
+	return out, offsets
+}
+
+// skipEscape 跳过从 p[i]（ESC）开始的转义序列，返回序列最后一个字节的位置
+func skipEscape(p []byte, i int) int {
+
+	if i+1 >= len(p) {
+		return i
+	}
+
+	switch p[i+1] {
+	case '[':
+		// CSI：参数与中间字节之后以 0x40-0x7e 结束
+		for j := i + 2; j < len(p); j++ {
+			if p[j] >= 0x40 && p[j] <= 0x7e {
+				return j
+			}
+		}
+		return len(p) - 1
+	case ']', 'P', '_', '^', 'X':
+		// OSC、DCS 等字符串序列：以 BEL 或 ST（ESC \）结束
+		for j := i + 2; j < len(p); j++ {
+			if p[j] == 0x07 {
+				return j
+			}
+			if p[j] == 0x1b && j+1 < len(p) && p[j+1] == '\\' {
+				return j + 1
+			}
+		}
+		return len(p) - 1
+	case '(', ')', '*', '+', '#', '%':
+		// 字符集选择等带一个参数字节的序列
+		if i+2 < len(p) {
+			return i + 2
+		}
+		return len(p) - 1
+	default:
+		return i + 1
+	}
+}
@@ -0,0 +1,64 @@
+package terminal
+
+import "testing"
+
+func TestStripANSI(t *testing.T) {
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain text", "hello\tworld\n", "hello\tworld\n"},
+		{"SGR", "\x1b[1;31mred\x1b[0m", "red"},
+		{"backspace", "ab\bc", "ac"},
+		{"backspace removes whole rune", "xé\b", "x"},
+		{"backspace at start", "\bx", "x"},
+		{"carriage return dropped", "a\r\nb", "a\nb"},
+		{"OSC with BEL", "\x1b]0;title\x07x", "x"},
+		{"OSC with ST", "\x1b]0;title\x1b\\x", "x"},
+		{"charset selection", "\x1b(Bx", "x"},
+		{"truncated CSI", "a\x1b[3", "a"},
+		{"lone ESC", "a\x1b", "a"},
+		{"DEL dropped", "a\x7fb", "ab"},
+	}
+	for _, tt := range tests {
+		if got := string(stripANSI([]byte(tt.in))); got != tt.want {
+			t.Errorf("%s: stripANSI(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestStripANSIMappedOffsets(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		offsets []int
+	}{
+		{"escape skipped", "a\x1b[0mb", "ab", []int{0, 5}},
+		{"backspace drops offset", "ab\bc", "ac", []int{0, 3}},
+		{"multi-byte backspace", "é\bz", "z", []int{3}},
+		{"carriage return", "ab\rcd", "abcd", []int{0, 1, 3, 4}},
+	}
+	for _, tt := range tests {
+		out, offsets := stripANSIMapped([]byte(tt.in), true)
+		if string(out) != tt.want {
+			t.Errorf("%s: text = %q, want %q", tt.name, out, tt.want)
+		}
+		if len(offsets) != len(out) {
+			t.Fatalf("%s: %d offsets for %d bytes", tt.name, len(offsets), len(out))
+		}
+		for i := range tt.offsets {
+			if offsets[i] != tt.offsets[i] {
+				t.Errorf("%s: offsets = %v, want %v", tt.name, offsets, tt.offsets)
+				break
+			}
+		}
+	}
+
+	if _, offsets := stripANSIMapped([]byte("abc"), false); offsets != nil {
+		t.Errorf("unmapped offsets = %v, want nil", offsets)
+	}
+}
@@ -0,0 +1,66 @@
+package terminal
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// loopbackHost 默认监听地址，仅本机可访问
+const loopbackHost = "127.0.0.1"
+
+// defaultAllowedOrigins Wails 资源服务器在各平台及开发模式下的 Origin
+var defaultAllowedOrigins = []string{
+	"wails://wails",
+	"wails://wails.localhost",
+	"http://wails.localhost",
+	"https://wails.localhost",
+	"http://localhost:34115",
+	"http://127.0.0.1:34115",
+}
+
+// newAuthToken 生成每次启动随机的连接令牌
+func newAuthToken() (string, error) {
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成终端连接令牌失败: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// tokenMatches 以常数时间比较令牌
+func tokenMatches(expected, actual string) bool {
+
+	if expected == "" || actual == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) == 1
+}
+
+// checkOrigin 只允许来自 Wails 前端（及设置中额外允许）的 Origin
+//
+// 没有 Origin 头的请求来自非浏览器客户端，仍需通过令牌校验。
+func (wsm *WebSocketManager) checkOrigin(r *http.Request) bool {
+
+	origin := strings.ToLower(strings.TrimSuffix(r.Header.Get("Origin"), "/"))
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range wsm.manager.allowedOrigins() {
+		if origin == strings.ToLower(strings.TrimSuffix(allowed, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize 校验连接令牌
+func (wsm *WebSocketManager) authorize(r *http.Request) bool {
+
+	return tokenMatches(wsm.manager.token, r.URL.Query().Get("token"))
+}
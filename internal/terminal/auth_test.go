@@ -0,0 +1,86 @@
+package terminal
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"edex-ui-golang/internal/models"
+)
+
+func TestTokenMatches(t *testing.T) {
+
+	tests := []struct {
+		name             string
+		expected, actual string
+		want             bool
+	}{
+		{"match", "secret-token", "secret-token", true},
+		{"wrong token", "secret-token", "secret-tokeX", false},
+		{"length mismatch", "secret-token", "secret", false},
+		{"prefix longer", "secret", "secret-token", false},
+		{"empty token", "secret-token", "", false},
+		{"not initialized", "", "", false},
+	}
+	for _, tt := range tests {
+		if got := tokenMatches(tt.expected, tt.actual); got != tt.want {
+			t.Errorf("%s: tokenMatches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		settings models.Settings
+		origin   string
+		want     bool
+	}{
+		{"wails frontend", models.Settings{}, "wails://wails", true},
+		{"dev server with trailing slash", models.Settings{}, "http://localhost:34115/", true},
+		{"case insensitive", models.Settings{}, "HTTP://WAILS.LOCALHOST", true},
+		{"foreign origin", models.Settings{}, "https://evil.example", false},
+		{"similar port", models.Settings{}, "http://localhost:3411", false},
+		{"empty Origin from non-browser client", models.Settings{}, "", true},
+		{"configured origin", models.Settings{TerminalAllowedOrigins: []string{"https://tools.example/"}}, "https://tools.example", true},
+		// 远程访问只改变监听地址，不会放开 Origin 检查
+		{"remote without explicit origins", models.Settings{AllowRemoteTerminal: true}, "http://192.168.1.20:8080", false},
+		{"remote frontend", models.Settings{AllowRemoteTerminal: true}, "wails://wails", true},
+		{"remote non-browser client", models.Settings{AllowRemoteTerminal: true}, "", true},
+	}
+	for _, tt := range tests {
+		settings := tt.settings
+		wsm := NewWebSocketManager(NewManager(&settings))
+
+		r := httptest.NewRequest("GET", "/webterminal", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := wsm.checkOrigin(r); got != tt.want {
+			t.Errorf("%s: checkOrigin(%q) = %v, want %v", tt.name, tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+
+	m := NewManager(&models.Settings{})
+	defer m.Close()
+	m.token = "secret-token"
+	wsm := NewWebSocketManager(m)
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"?token=secret-token", true},
+		{"?token=wrong", false},
+		{"", false},
+		{"?share=secret-token", false},
+	}
+	for _, tt := range tests {
+		if got := wsm.authorize(httptest.NewRequest("GET", "/webterminal"+tt.query, nil)); got != tt.want {
+			t.Errorf("authorize(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package terminal
+
+import (
+	"errors"
+	"os/exec"
+
+	"edex-ui-golang/internal/models"
+)
+
+// sessionBackend 会话后端，负责实际的输入输出（本地 PTY 等）
+type sessionBackend interface {
+	Read(p []byte) (int, error)
+	Write(p []byte) (int, error)
+	// Resize 调整终端尺寸
+	Resize(rows, cols uint16) error
+	// Close 关闭后端并终止相关进程
+	Close() error
+	// Wait 阻塞直到后端结束，返回退出状态
+	Wait() ExitStatus
+	// Process 返回后端对应的本地子进程，不存在或无法获取时为 nil
+	Process() *exec.Cmd
+}
+
+// foregroundReporter 能报告前台进程组的后端（类 Unix 系统上的本地 PTY）
+type foregroundReporter interface {
+	ForegroundPid() (int, error)
+}
+
+// hangupBackend 能在关闭前通知进程挂断的后端（类 Unix 系统上的本地 PTY）
+type hangupBackend interface {
+	// Hangup 向 shell 与前台作业所在的进程组发送 SIGHUP
+	Hangup() error
+	// Lingering 报告会话中是否仍有进程存活，包括 shell 退出后留下的后台作业
+	Lingering() bool
+}
+
+// 信号的发送目标
+const (
+	SignalTargetForeground = "foreground" // 前台进程组，即正在运行的作业
+	SignalTargetSession    = "session"    // 会话中的所有进程，包括 shell 与后台作业
+)
+
+// 信号的发送方式
+const (
+	signalMethodKill   = "kill"   // 直接发送给本地进程组
+	signalMethodTTY    = "tty"    // 向终端写入控制字符，由行规程转换为信号
+	signalMethodRemote = "remote" // 通过 SSH 请求由远程发送
+)
+
+// errSignalUnsupported 后端无法直接发送该信号，由会话改用终端控制字符
+var errSignalUnsupported = errors.New("后端不支持直接发送该信号")
+
+// signalBackend 能直接向进程发送信号的后端（类 Unix 系统上的本地 PTY、SSH）
+type signalBackend interface {
+	// Signal 向 target 发送信号，返回发送方式与收到信号的进程组
+	Signal(name, target string) (method string, pgids []int, err error)
+}
+
+// ExitStatus 会话进程的退出状态
+type ExitStatus struct {
+	Code   int    `json:"code"`             // 退出码，被信号终止或无法获取时为 -1
+	Signal string `json:"signal,omitempty"` // 终止进程的信号名，例如 SIGKILL
+}
+
+// spawnSpec 启动本地 PTY 所需的参数，重启 shell 时原样复用
+type spawnSpec struct {
+	Path string
+	Args []string // 完整参数列表，Args[0] 为程序名
+	Dir  string
+	Env  []string // 形如 key=value，为空时继承当前进程环境
+	Cols uint16
+	Rows uint16
+	// 资源限制，仅在 Linux 上生效
+	Limits models.ShellLimits
+}
@@ -0,0 +1,139 @@
+package terminal
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"edex-ui-golang/internal/models"
+)
+
+// EventBroadcastChanged 广播输入分组变化事件，数据为 models.BroadcastState
+const EventBroadcastChanged = "broadcast-changed"
+
+// broadcastGroup 广播输入分组
+//
+// 广播开启时，写入分组内任一会话的输入会同时发送给分组内的所有会话。
+type broadcastGroup struct {
+	mu      sync.RWMutex
+	enabled bool
+	members map[string]struct{}
+}
+
+// newBroadcastGroup 创建广播输入分组
+func newBroadcastGroup() *broadcastGroup {
+
+	return &broadcastGroup{members: make(map[string]struct{})}
+}
+
+// state 返回分组状态
+func (g *broadcastGroup) state() models.BroadcastState {
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	sessions := make([]string, 0, len(g.members))
+	for id := range g.members {
+		sessions = append(sessions, id)
+	}
+	sort.Strings(sessions)
+	return models.BroadcastState{Enabled: g.enabled, Sessions: sessions}
+}
+
+// targets 返回输入应写入的会话 ID，源会话不在分组内或广播关闭时返回 nil
+func (g *broadcastGroup) targets(sessionID string) []string {
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if !g.enabled {
+		return nil
+	}
+	if _, ok := g.members[sessionID]; !ok {
+		return nil
+	}
+
+	ids := make([]string, 0, len(g.members))
+	for id := range g.members {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// AddToBroadcast 将会话加入广播分组
+func (wsm *WebSocketManager) AddToBroadcast(sessionID string) error {
+
+	session, exists := wsm.sessions.Get(sessionID)
+	if !exists {
+		return fmt.Errorf("会话不存在: %s", sessionID)
+	}
+	if session.Kind == SessionKindPlayback {
+		return fmt.Errorf("回放会话不能加入广播分组")
+	}
+
+	wsm.broadcast.mu.Lock()
+	wsm.broadcast.members[sessionID] = struct{}{}
+	wsm.broadcast.mu.Unlock()
+
+	wsm.notifyBroadcast()
+	return nil
+}
+
+// RemoveFromBroadcast 将会话移出广播分组
+func (wsm *WebSocketManager) RemoveFromBroadcast(sessionID string) {
+
+	wsm.broadcast.mu.Lock()
+	_, existed := wsm.broadcast.members[sessionID]
+	delete(wsm.broadcast.members, sessionID)
+	wsm.broadcast.mu.Unlock()
+
+	if existed {
+		wsm.notifyBroadcast()
+	}
+}
+
+// SetBroadcast 开启或关闭广播输入
+func (wsm *WebSocketManager) SetBroadcast(enabled bool) {
+
+	wsm.broadcast.mu.Lock()
+	changed := wsm.broadcast.enabled != enabled
+	wsm.broadcast.enabled = enabled
+	wsm.broadcast.mu.Unlock()
+
+	if changed {
+		log.Printf("广播输入已%s", map[bool]string{true: "开启", false: "关闭"}[enabled])
+		wsm.notifyBroadcast()
+	}
+}
+
+// BroadcastState 返回广播分组状态
+func (wsm *WebSocketManager) BroadcastState() models.BroadcastState {
+
+	return wsm.broadcast.state()
+}
+
+// notifyBroadcast 通知前端广播分组变化
+func (wsm *WebSocketManager) notifyBroadcast() {
+
+	wsm.manager.emit(EventBroadcastChanged, wsm.broadcast.state())
+}
+
+// input 将客户端输入写入会话，广播开启时同时写入分组内的其他会话
+//
+// 已退出的其他会话不接收广播，避免一次回车把它们全部重启。
+func (wsm *WebSocketManager) input(session *TerminalSession, p []byte) {
+
+	session.write(p)
+
+	for _, id := range wsm.broadcast.targets(session.ID) {
+		if id == session.ID {
+			continue
+		}
+		peer, exists := wsm.sessions.Get(id)
+		if !exists || !peer.running() {
+			continue
+		}
+		peer.write(p)
+	}
+}
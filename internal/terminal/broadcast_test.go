@@ -0,0 +1,190 @@
+package terminal
+
+import (
+	"io"
+	"os/exec"
+	"reflect"
+	"sync"
+	"testing"
+
+	"edex-ui-golang/internal/models"
+)
+
+// inputBackend 记录写入内容的后端，不产生输出
+type inputBackend struct {
+	mu        sync.Mutex
+	input     []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newInputBackend() *inputBackend {
+	return &inputBackend{closed: make(chan struct{})}
+}
+
+func (b *inputBackend) Read(p []byte) (int, error) {
+
+	<-b.closed
+	return 0, io.EOF
+}
+
+func (b *inputBackend) Write(p []byte) (int, error) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.input = append(b.input, p...)
+	return len(p), nil
+}
+
+func (b *inputBackend) Resize(rows, cols uint16) error { return nil }
+
+func (b *inputBackend) Close() error {
+
+	b.closeOnce.Do(func() { close(b.closed) })
+	return nil
+}
+
+func (b *inputBackend) Wait() ExitStatus {
+
+	<-b.closed
+	return ExitStatus{}
+}
+
+func (b *inputBackend) Process() *exec.Cmd { return nil }
+
+// received 返回已写入的内容
+func (b *inputBackend) received() string {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.input)
+}
+
+// addInputSession 注册一个使用 inputBackend 的会话
+func addInputSession(t *testing.T, wsm *WebSocketManager, id string) (*TerminalSession, *inputBackend) {
+
+	t.Helper()
+
+	backend := newInputBackend()
+	session := newTerminalSession(id, minScrollbackSize)
+	session.backend = backend
+	if err := wsm.sessions.add(session); err != nil {
+		t.Fatal(err)
+	}
+	return session, backend
+}
+
+func TestBroadcastMembership(t *testing.T) {
+
+	m := NewManager(&models.Settings{})
+	defer m.Close()
+	wsm := m.websocketManager
+
+	var events []models.BroadcastState
+	m.SetEventEmitter(func(event string, data interface{}) {
+		if state, ok := data.(models.BroadcastState); ok && event == EventBroadcastChanged {
+			events = append(events, state)
+		}
+	})
+
+	addInputSession(t, wsm, "b")
+	addInputSession(t, wsm, "a")
+	playback, _ := addInputSession(t, wsm, "play")
+	playback.Kind = SessionKindPlayback
+
+	for _, id := range []string{"b", "a"} {
+		if err := wsm.AddToBroadcast(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := wsm.AddToBroadcast("missing"); err == nil {
+		t.Error("unknown session added to the group")
+	}
+	// 回放会话只能观看，不能加入分组
+	if err := wsm.AddToBroadcast("play"); err == nil {
+		t.Error("playback session added to the group")
+	}
+	if got := wsm.BroadcastState(); !reflect.DeepEqual(got, models.BroadcastState{Sessions: []string{"a", "b"}}) {
+		t.Errorf("state = %+v, want sorted members a, b", got)
+	}
+
+	wsm.SetBroadcast(true)
+	wsm.SetBroadcast(true)
+	wsm.RemoveFromBroadcast("a")
+	wsm.RemoveFromBroadcast("a")
+	if got := wsm.BroadcastState(); !reflect.DeepEqual(got, models.BroadcastState{Enabled: true, Sessions: []string{"b"}}) {
+		t.Errorf("state = %+v, want enabled with b", got)
+	}
+	// 重复的开启与移出不产生事件
+	if len(events) != 4 {
+		t.Errorf("got %d change events, want 4: %+v", len(events), events)
+	}
+}
+
+func TestBroadcastInput(t *testing.T) {
+
+	m := NewManager(&models.Settings{})
+	defer m.Close()
+	wsm := m.websocketManager
+
+	source, sourceBackend := addInputSession(t, wsm, "source")
+	_, peerBackend := addInputSession(t, wsm, "peer")
+	exited, exitedBackend := addInputSession(t, wsm, "exited")
+	exited.exitStatus = &ExitStatus{Code: 1}
+	outsider, outsiderBackend := addInputSession(t, wsm, "outsider")
+
+	for _, id := range []string{"source", "peer", "exited"} {
+		if err := wsm.AddToBroadcast(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 广播关闭时只写入源会话
+	wsm.input(source, []byte("a"))
+	wsm.SetBroadcast(true)
+	// 已退出的会话不接收广播，换行不会把它重启；源会话只写入一次
+	wsm.input(source, []byte("b\r"))
+	// 不在分组内的会话的输入不广播
+	wsm.input(outsider, []byte("c"))
+
+	tests := []struct {
+		name    string
+		backend *inputBackend
+		want    string
+	}{
+		{"source", sourceBackend, "ab\r"},
+		{"peer", peerBackend, "b\r"},
+		{"exited", exitedBackend, ""},
+		{"outsider", outsiderBackend, "c"},
+	}
+	for _, tt := range tests {
+		if got := tt.backend.received(); got != tt.want {
+			t.Errorf("%s received %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveSessionLeavesBroadcastGroup(t *testing.T) {
+
+	m := NewManager(&models.Settings{})
+	defer m.Close()
+	wsm := m.websocketManager
+
+	session, backend := addInputSession(t, wsm, "gone")
+	addInputSession(t, wsm, "kept")
+	for _, id := range []string{"gone", "kept"} {
+		if err := wsm.AddToBroadcast(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wsm.removeSession(session)
+	if got := wsm.BroadcastState().Sessions; !reflect.DeepEqual(got, []string{"kept"}) {
+		t.Errorf("members after close = %v, want [kept]", got)
+	}
+	select {
+	case <-backend.closed:
+	default:
+		t.Error("backend not closed with the session")
+	}
+}
@@ -0,0 +1,276 @@
+package terminal
+
+import (
+	"compress/flate"
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 客户端发送参数
+const (
+	// clientWriteTimeout 单次写入的超时，超时的连接被视为失效
+	clientWriteTimeout = 10 * time.Second
+	// outputBatchWindow 合并连续输出时的最长等待时间
+	outputBatchWindow = 5 * time.Millisecond
+	// outputBatchSize 合并后单个输出帧的最大字节数
+	outputBatchSize = 64 * 1024
+	// sendQueueFrames 发送队列的最大帧数
+	sendQueueFrames = 1024
+	// sendQueueBytes 发送队列允许积压的最大字节数
+	sendQueueBytes = 8 * 1024 * 1024
+)
+
+// outFrame 等待发送的一帧
+type outFrame struct {
+	text bool
+	// 关闭帧，data 为关闭码与原因，发送后断开连接
+	close bool
+	// 强制加入队列的帧（回放、关闭帧）不计入积压字节数
+	forced bool
+	data   []byte
+}
+
+// queuedSize 返回帧计入积压字节数的大小
+func (f outFrame) queuedSize() int64 {
+
+	if f.forced {
+		return 0
+	}
+	return int64(len(f.data))
+}
+
+// wsClient 附加到会话的一个 WebSocket 连接
+//
+// 输出先进入有界的发送队列，由 writeLoop 合并后写出，因此慢速客户端不会阻塞 PTY 读取。
+// 队列积压超过上限的客户端被视为慢速消费者并断开，重新连接时会收到输出历史回放。
+type wsClient struct {
+	id          string
+	conn        *websocket.Conn
+	remoteAddr  string
+	connectedAt time.Time
+	// 观察者只接收输出，输入与调整尺寸被丢弃
+	readOnly bool
+	// 观察者使用的分享令牌，用于撤销
+	shareToken string
+	// 协商的控制协议版本，0 表示旧版客户端
+	protocol int
+	// 发送队列及其积压字节数
+	queue  chan outFrame
+	queued int64
+	// 连接关闭后关闭，writeLoop 随之退出
+	gone     chan struct{}
+	goneOnce sync.Once
+}
+
+// newWSClient 创建客户端并启动发送协程
+func newWSClient(conn *websocket.Conn, remoteAddr string) *wsClient {
+
+	client := &wsClient{
+		id:          newSessionID(),
+		conn:        conn,
+		remoteAddr:  remoteAddr,
+		connectedAt: time.Now(),
+		queue:       make(chan outFrame, sendQueueFrames),
+		gone:        make(chan struct{}),
+	}
+	if conn.Subprotocol() == protocolV1 {
+		client.protocol = ProtocolVersion
+	}
+
+	// 仅在协商了 permessage-deflate 时生效；终端输出优先考虑速度
+	_ = conn.SetCompressionLevel(flate.BestSpeed)
+
+	go client.writeLoop()
+	return client
+}
+
+// writeBinary 将输出加入发送队列，p 在发送前不得被修改
+func (c *wsClient) writeBinary(p []byte) {
+	c.enqueue(outFrame{data: p}, false)
+}
+
+// sendControl 向 v1 客户端发送控制消息，旧版客户端忽略
+func (c *wsClient) sendControl(msgType string, data interface{}) {
+
+	if c.protocol < ProtocolVersion {
+		return
+	}
+
+	frame, err := encodeControl(msgType, data)
+	if err != nil {
+		log.Printf("编码控制消息失败: %v", err)
+		return
+	}
+	c.writeText(frame)
+}
+
+// sendControlRaw 向 v1 客户端发送已编码内容的控制消息
+func (c *wsClient) sendControlRaw(msgType string, data []byte) {
+
+	if c.protocol < ProtocolVersion {
+		return
+	}
+
+	frame, err := encodeControl(msgType, json.RawMessage(data))
+	if err != nil {
+		frame, _ = encodeControl(msgType, nil)
+	}
+	c.writeText(frame)
+}
+
+// writeText 将文本帧加入发送队列
+func (c *wsClient) writeText(p []byte) {
+	c.enqueue(outFrame{text: true, data: p}, false)
+}
+
+// enqueue 将一帧加入发送队列
+//
+// force 为 true 时不检查也不计入积压字节数，用于附加时的输出历史回放，
+// 避免接近上限的回放使客户端在随后的第一帧实时输出时被断开。
+// 队列已满或积压过多时断开客户端。
+func (c *wsClient) enqueue(frame outFrame, force bool) bool {
+
+	select {
+	case <-c.gone:
+		return false
+	default:
+	}
+
+	frame.forced = force
+	size := frame.queuedSize()
+	if !force && atomic.LoadInt64(&c.queued)+size > sendQueueBytes {
+		c.drop("输出积压超过上限")
+		return false
+	}
+
+	atomic.AddInt64(&c.queued, size)
+	select {
+	case c.queue <- frame:
+		return true
+	default:
+		atomic.AddInt64(&c.queued, -size)
+		c.drop("发送队列已满")
+		return false
+	}
+}
+
+// closeWith 发送完队列中已有的帧后以关闭帧结束连接
+func (c *wsClient) closeWith(code int, reason string) {
+
+	if !c.enqueue(outFrame{close: true, data: websocket.FormatCloseMessage(code, reason)}, true) {
+		c.shutdown()
+	}
+}
+
+// drop 断开跟不上输出速度的客户端
+func (c *wsClient) drop(reason string) {
+
+	log.Printf("客户端 %s %s，断开连接", c.remoteAddr, reason)
+	c.shutdown()
+}
+
+// shutdown 关闭连接并停止发送协程，可重复调用
+func (c *wsClient) shutdown() {
+
+	c.goneOnce.Do(func() {
+		close(c.gone)
+		c.conn.Close()
+	})
+}
+
+// writeLoop 依次发送队列中的帧
+//
+// 连续的输出帧在 outputBatchWindow 内合并为一帧，减少大量输出时的帧数。
+// 这是唯一写连接的协程，因此不需要额外的写锁。
+func (c *wsClient) writeLoop() {
+
+	for {
+		var frame outFrame
+		select {
+		case <-c.gone:
+			return
+		case frame = <-c.queue:
+			atomic.AddInt64(&c.queued, -frame.queuedSize())
+		}
+
+		if frame.text || frame.close {
+			if !c.writeControlFrame(frame) {
+				return
+			}
+			continue
+		}
+
+		batch, pending, ok := c.collect(frame.data)
+		if !ok {
+			return
+		}
+		if !c.writeFrame(websocket.BinaryMessage, batch) {
+			return
+		}
+		if pending != nil && !c.writeControlFrame(*pending) {
+			return
+		}
+	}
+}
+
+// writeControlFrame 发送文本帧或关闭帧，关闭帧发送后断开连接并返回 false
+func (c *wsClient) writeControlFrame(frame outFrame) bool {
+
+	if !frame.close {
+		return c.writeFrame(websocket.TextMessage, frame.data)
+	}
+	_ = c.conn.WriteControl(websocket.CloseMessage, frame.data, time.Now().Add(clientWriteTimeout))
+	c.shutdown()
+	return false
+}
+
+// collect 在合并窗口内收集后续输出帧
+//
+// 遇到文本帧或关闭帧时停止收集并将其返回，保证控制消息与输出的顺序不变。
+func (c *wsClient) collect(first []byte) ([]byte, *outFrame, bool) {
+
+	batch := first
+	owned := false
+
+	timer := time.NewTimer(outputBatchWindow)
+	defer timer.Stop()
+
+	for len(batch) < outputBatchSize {
+		select {
+		case <-c.gone:
+			return nil, nil, false
+		case <-timer.C:
+			return batch, nil, true
+		case next := <-c.queue:
+			atomic.AddInt64(&c.queued, -next.queuedSize())
+			if next.text || next.close {
+				return batch, &next, true
+			}
+			// 队列中的数据在多个客户端之间共享，合并前先复制
+			if !owned {
+				merged := make([]byte, 0, outputBatchSize+len(next.data))
+				batch = append(merged, batch...)
+				owned = true
+			}
+			batch = append(batch, next.data...)
+		}
+	}
+	return batch, nil, true
+}
+
+// writeFrame 带写超时地发送一帧，失败时断开连接
+func (c *wsClient) writeFrame(messageType int, p []byte) bool {
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(clientWriteTimeout))
+	if err := c.conn.WriteMessage(messageType, p); err != nil {
+		log.Printf("发送到客户端 %s 失败: %v", c.remoteAddr, err)
+		c.shutdown()
+		return false
+	}
+	return true
+}
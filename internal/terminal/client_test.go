@@ -0,0 +1,157 @@
+package terminal
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newConnPair 建立一对 WebSocket 连接，返回服务端与客户端
+func newConnPair(t *testing.T) (*websocket.Conn, *websocket.Conn) {
+
+	t.Helper()
+
+	accepted := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		accepted <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { peer.Close() })
+
+	conn := <-accepted
+	t.Cleanup(func() { conn.Close() })
+	return conn, peer
+}
+
+// newQueueClient 创建未启动发送协程的客户端，便于检查队列状态
+func newQueueClient(conn *websocket.Conn) *wsClient {
+
+	return &wsClient{
+		conn:       conn,
+		remoteAddr: "test",
+		queue:      make(chan outFrame, sendQueueFrames),
+		gone:       make(chan struct{}),
+	}
+}
+
+func TestClientCollectBatchesOutput(t *testing.T) {
+
+	c := newQueueClient(nil)
+	for _, frame := range []outFrame{
+		{data: []byte("a")},
+		{data: []byte("b")},
+		{text: true, data: []byte("ctl")},
+		{data: []byte("c")},
+	} {
+		c.enqueue(frame, false)
+	}
+
+	// 文本帧之前的输出合并为一帧，文本帧随后返回，顺序不变
+	batch, pending, ok := c.collect([]byte("0"))
+	if !ok || string(batch) != "0ab" {
+		t.Fatalf("batch = %q, %v, want \"0ab\"", batch, ok)
+	}
+	if pending == nil || !pending.text || string(pending.data) != "ctl" {
+		t.Fatalf("pending = %+v, want text frame", pending)
+	}
+	if got := atomic.LoadInt64(&c.queued); got != 1 {
+		t.Errorf("queued = %d, want 1", got)
+	}
+
+	// 合并窗口结束时返回已收集的输出
+	<-c.queue
+	batch, pending, ok = c.collect([]byte("x"))
+	if !ok || string(batch) != "x" || pending != nil {
+		t.Errorf("idle collect = %q, %+v, %v", batch, pending, ok)
+	}
+
+	// 达到 outputBatchSize 后停止合并，剩余的帧留在队列中
+	big := bytes.Repeat([]byte("z"), outputBatchSize)
+	c.enqueue(outFrame{data: big}, false)
+	c.enqueue(outFrame{data: big}, false)
+	batch, _, _ = c.collect([]byte("y"))
+	if len(batch) != outputBatchSize+1 {
+		t.Errorf("batch size = %d, want %d", len(batch), outputBatchSize+1)
+	}
+	if len(c.queue) != 1 {
+		t.Errorf("queue length = %d, want 1", len(c.queue))
+	}
+}
+
+func TestClientDropsOnBacklog(t *testing.T) {
+
+	conn, _ := newConnPair(t)
+	c := newQueueClient(conn)
+
+	chunk := make([]byte, 1024*1024)
+	for i := 0; i < sendQueueBytes/len(chunk); i++ {
+		if !c.enqueue(outFrame{data: chunk}, false) {
+			t.Fatalf("frame %d rejected below the backlog limit", i)
+		}
+	}
+	if c.enqueue(outFrame{data: chunk}, false) {
+		t.Fatal("frame accepted beyond the backlog limit")
+	}
+	select {
+	case <-c.gone:
+	default:
+		t.Fatal("slow client not dropped")
+	}
+	if c.enqueue(outFrame{data: []byte("late")}, false) {
+		t.Error("frame accepted after drop")
+	}
+}
+
+func TestClientReplayThenLiveOutput(t *testing.T) {
+
+	conn, peer := newConnPair(t)
+	c := newQueueClient(conn)
+
+	// 接近上限的回放不计入积压，随后的实时输出不会导致断开
+	replay := bytes.Repeat([]byte("r"), sendQueueBytes-1024)
+	live := bytes.Repeat([]byte("l"), 4096)
+	if !c.enqueue(outFrame{data: replay}, true) {
+		t.Fatal("replay rejected")
+	}
+	if !c.enqueue(outFrame{data: live}, false) {
+		t.Fatal("live output after replay dropped the client")
+	}
+	if got := atomic.LoadInt64(&c.queued); got != int64(len(live)) {
+		t.Errorf("queued = %d, want %d", got, len(live))
+	}
+
+	go c.writeLoop()
+	defer c.shutdown()
+
+	var received []byte
+	_ = peer.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for len(received) < len(replay)+len(live) {
+		_, data, err := peer.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		received = append(received, data...)
+	}
+	if !bytes.Equal(received, append(replay, live...)) {
+		t.Error("replay and live output arrived out of order")
+	}
+	if got := atomic.LoadInt64(&c.queued); got != 0 {
+		t.Errorf("queued after drain = %d, want 0", got)
+	}
+}
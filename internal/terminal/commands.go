@@ -0,0 +1,227 @@
+package terminal
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"edex-ui-golang/internal/models"
+)
+
+// maxCommandLog 每个会话保留的命令记录数
+const maxCommandLog = 1000
+
+// commandLog 通过 OSC 133 标记识别的命令记录，由 TerminalSession.mu 保护
+//
+// 标记含义（FinalTerm 约定）：A 提示符开始，B 命令输入开始，
+// C 命令开始执行（输出开始），D[;退出码] 命令结束。
+type commandLog struct {
+	// 是否收到过任何 OSC 133 标记
+	enabled bool
+	// 最近一次提示符与命令输入的起点，未知时为 -1
+	promptStart int64
+	inputStart  int64
+	commands    []models.TerminalCommand
+	nextIndex   int
+}
+
+// shellMark 解析后的 OSC 133 标记
+type shellMark struct {
+	kind   byte
+	params []string
+}
+
+// parseOSC133 解析 OSC 133 标记，内容形如 133;D;0
+func parseOSC133(payload []byte) (shellMark, bool) {
+
+	text := string(payload)
+	if !strings.HasPrefix(text, "133;") || len(text) < 5 {
+		return shellMark{}, false
+	}
+
+	fields := strings.Split(text[4:], ";")
+	if len(fields[0]) != 1 || fields[0][0] < 'A' || fields[0][0] > 'D' {
+		return shellMark{}, false
+	}
+	return shellMark{kind: fields[0][0], params: fields[1:]}, true
+}
+
+// handleShellMark 根据 OSC 133 标记更新命令记录
+//
+// start 与 end 为标记序列在输出流中的起止偏移。调用时该段输出已写入输出历史。
+func (ts *TerminalSession) handleShellMark(mark shellMark, start, end int64) {
+
+	var events []func()
+	var finished []models.TerminalCommand
+
+	ts.mu.Lock()
+	log := &ts.commands
+	if !log.enabled {
+		log.enabled = true
+		log.promptStart, log.inputStart = -1, -1
+	}
+
+	switch mark.kind {
+	case 'A':
+		// 缺少 D 标记的命令在下一个提示符出现时结束
+		if cmd, ok := ts.finishCommandLocked(start, nil); ok {
+			events = append(events, ts.commandEvent(EventCommandFinished, cmd))
+		}
+		log.promptStart, log.inputStart = start, -1
+	case 'B':
+		log.inputStart = end
+	case 'C':
+		if cmd, ok := ts.finishCommandLocked(start, nil); ok {
+			events = append(events, ts.commandEvent(EventCommandFinished, cmd))
+		}
+		cmd := ts.startCommandLocked(mark, start, end)
+		events = append(events, ts.commandEvent(EventCommandStarted, cmd))
+	case 'D':
+		var code *int
+		if len(mark.params) > 0 {
+			if value, err := strconv.Atoi(strings.TrimSpace(mark.params[0])); err == nil {
+				code = &value
+			}
+		}
+		if cmd, ok := ts.finishCommandLocked(start, code); ok {
+			events = append(events, ts.commandEvent(EventCommandFinished, cmd))
+			finished = append(finished, cmd)
+		}
+	}
+	ts.mu.Unlock()
+
+	for _, emit := range events {
+		emit()
+	}
+	for _, cmd := range finished {
+		ts.commandFinished(cmd)
+	}
+}
+
+// startCommandLocked 记录开始执行的命令
+func (ts *TerminalSession) startCommandLocked(mark shellMark, start, end int64) models.TerminalCommand {
+
+	log := &ts.commands
+	promptStart := log.promptStart
+	if promptStart < 0 {
+		promptStart = start
+	}
+
+	cmd := models.TerminalCommand{
+		SessionID:    ts.ID,
+		Index:        log.nextIndex,
+		Command:      ts.commandText(mark, log.inputStart, start),
+		Cwd:          ts.Cwd,
+		PromptStart:  promptStart,
+		CommandStart: log.inputStart,
+		OutputStart:  end,
+		OutputEnd:    -1,
+		StartedAt:    time.Now().UnixMilli(),
+		Running:      true,
+	}
+	log.nextIndex++
+	log.promptStart, log.inputStart = -1, -1
+
+	log.commands = append(log.commands, cmd)
+	if len(log.commands) > maxCommandLog {
+		log.commands = append([]models.TerminalCommand(nil), log.commands[len(log.commands)-maxCommandLog:]...)
+	}
+	return cmd
+}
+
+// commandText 取得命令文本
+//
+// 优先使用标记中的 cmdline_url 参数，否则从输出历史中截取命令输入区间并去除转义序列。
+func (ts *TerminalSession) commandText(mark shellMark, inputStart, inputEnd int64) string {
+
+	for _, param := range mark.params {
+		if value, ok := strings.CutPrefix(param, "cmdline_url="); ok {
+			if text, err := url.QueryUnescape(value); err == nil {
+				return text
+			}
+		}
+	}
+
+	if inputStart < 0 || inputStart >= inputEnd {
+		return ""
+	}
+	data, start := ts.scrollback.Range(inputStart, inputEnd)
+	if start != inputStart {
+		// 输入区间已被挤出输出历史
+		return ""
+	}
+	return strings.TrimSpace(string(stripANSI(data)))
+}
+
+// finishCommandLocked 结束正在运行的命令，没有运行中的命令时返回 false
+func (ts *TerminalSession) finishCommandLocked(end int64, code *int) (models.TerminalCommand, bool) {
+
+	commands := ts.commands.commands
+	if len(commands) == 0 || !commands[len(commands)-1].Running {
+		return models.TerminalCommand{}, false
+	}
+
+	cmd := &commands[len(commands)-1]
+	now := time.Now().UnixMilli()
+	cmd.OutputEnd = end
+	cmd.FinishedAt = now
+	cmd.Duration = now - cmd.StartedAt
+	cmd.ExitCode = code
+	cmd.Running = false
+	return *cmd, true
+}
+
+// finishRunningCommand 进程退出时结束仍在运行的命令
+func (ts *TerminalSession) finishRunningCommand() {
+
+	_, end := ts.scrollback.Window()
+
+	ts.mu.Lock()
+	cmd, ok := ts.finishCommandLocked(end, nil)
+	ts.mu.Unlock()
+
+	if ok {
+		ts.commandEvent(EventCommandFinished, cmd)()
+	}
+}
+
+// commandEvent 返回发送命令事件的函数，在释放锁之后调用
+func (ts *TerminalSession) commandEvent(event string, cmd models.TerminalCommand) func() {
+
+	return func() {
+		if ts.emit != nil {
+			ts.emit(event, cmd)
+		}
+	}
+}
+
+// commandList 返回命令记录的副本
+func (ts *TerminalSession) commandList() []models.TerminalCommand {
+
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return append([]models.TerminalCommand{}, ts.commands.commands...)
+}
+
+// findCommand 按序号查找命令，index 小于 0 表示最近一条
+func (ts *TerminalSession) findCommand(index int) (models.TerminalCommand, error) {
+
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	commands := ts.commands.commands
+	if len(commands) == 0 {
+		return models.TerminalCommand{}, fmt.Errorf("会话 %s 没有命令记录，请确认已启用 shell 集成", ts.ID)
+	}
+	if index < 0 {
+		return commands[len(commands)-1], nil
+	}
+	for _, cmd := range commands {
+		if cmd.Index == index {
+			return cmd, nil
+		}
+	}
+	return models.TerminalCommand{}, fmt.Errorf("命令记录不存在: %d", index)
+}
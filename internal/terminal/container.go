@@ -0,0 +1,504 @@
+package terminal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"edex-ui-golang/internal/models"
+)
+
+// 容器引擎 API 参数
+const (
+	containerAPITimeout = 10 * time.Second
+	// exec 输出结束后等待引擎更新退出码的最长时间
+	containerExitWait = 2 * time.Second
+)
+
+// containerDefaultCommand 未指定命令时优先启动 bash，镜像中没有 bash 时退回 sh
+var containerDefaultCommand = []string{"/bin/sh", "-c", "if command -v bash >/dev/null 2>&1; then exec bash; else exec sh; fi"}
+
+// containerSocketCandidates 自动探测的 Docker/Podman API socket
+func containerSocketCandidates() []string {
+
+	candidates := []string{"/var/run/docker.sock"}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		candidates = append(candidates,
+			filepath.Join(runtimeDir, "podman", "podman.sock"),
+			filepath.Join(runtimeDir, "docker.sock"))
+	}
+	candidates = append(candidates, "/run/podman/podman.sock")
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, ".docker", "run", "docker.sock"))
+	}
+	return candidates
+}
+
+// resolveContainerSocket 确定容器引擎 API 的 socket 路径
+//
+// 优先使用设置中的路径，其次是 DOCKER_HOST / CONTAINER_HOST 中的 unix:// 地址，最后探测常见位置。
+func resolveContainerSocket(configured string) (string, error) {
+
+	if configured != "" {
+		return strings.TrimPrefix(configured, "unix://"), nil
+	}
+	for _, name := range []string{"DOCKER_HOST", "CONTAINER_HOST"} {
+		if host := os.Getenv(name); strings.HasPrefix(host, "unix://") {
+			return strings.TrimPrefix(host, "unix://"), nil
+		}
+	}
+	for _, candidate := range containerSocketCandidates() {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("未找到 Docker/Podman 的 API socket，请在设置中指定 containerSocket")
+}
+
+// containerClient 通过 unix socket 访问 Docker 兼容的 HTTP API（Podman 同样提供）
+type containerClient struct {
+	socket string
+	http   *http.Client
+}
+
+// newContainerClient 创建容器引擎 API 客户端
+func newContainerClient(socket string) *containerClient {
+
+	c := &containerClient{socket: socket}
+	c.http = &http.Client{
+		Timeout: containerAPITimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return c.dial(ctx)
+			},
+		},
+	}
+	return c
+}
+
+// dial 连接 API socket
+func (c *containerClient) dial(ctx context.Context) (net.Conn, error) {
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", c.socket)
+	if err != nil {
+		return nil, fmt.Errorf("连接容器引擎 %s 失败: %v", c.socket, err)
+	}
+	return conn, nil
+}
+
+// newRequest 构造 API 请求，body 非 nil 时编码为 JSON
+func (c *containerClient) newRequest(method, path string, body interface{}) (*http.Request, error) {
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	// 主机名只用于 Host 头，实际连接由 dial 决定
+	req, err := http.NewRequest(method, "http://docker"+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// do 发送 API 请求，out 非 nil 时解码 JSON 响应
+func (c *containerClient) do(method, path string, body, out interface{}) error {
+
+	req, err := c.newRequest(method, path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return containerAPIError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("解析容器引擎响应失败: %v", err)
+	}
+	return nil
+}
+
+// containerAPIError 将错误响应转换为 error，引擎的错误信息在 message 字段中
+func containerAPIError(resp *http.Response) error {
+
+	var payload struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if json.Unmarshal(data, &payload) == nil && payload.Message != "" {
+		return fmt.Errorf("容器引擎返回错误: %s", payload.Message)
+	}
+	return fmt.Errorf("容器引擎返回错误: %s", resp.Status)
+}
+
+// listContainers 列出正在运行的容器
+func (c *containerClient) listContainers() ([]models.Container, error) {
+
+	var items []struct {
+		ID     string   `json:"Id"`
+		Names  []string `json:"Names"`
+		Image  string   `json:"Image"`
+		State  string   `json:"State"`
+		Status string   `json:"Status"`
+	}
+	if err := c.do(http.MethodGet, "/containers/json", nil, &items); err != nil {
+		return nil, err
+	}
+
+	containers := make([]models.Container, 0, len(items))
+	for _, item := range items {
+		container := models.Container{
+			ID:     item.ID,
+			Image:  item.Image,
+			State:  item.State,
+			Status: item.Status,
+		}
+		if len(item.Names) > 0 {
+			container.Name = strings.TrimPrefix(item.Names[0], "/")
+		}
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+// checkContainer 确认容器存在且正在运行
+//
+// 对已停止的容器创建 exec 时引擎只返回笼统的冲突错误，先检查以便给出明确的提示。
+func (c *containerClient) checkContainer(id string) error {
+
+	var container struct {
+		State struct {
+			Running bool   `json:"Running"`
+			Status  string `json:"Status"`
+		} `json:"State"`
+	}
+	if err := c.do(http.MethodGet, "/containers/"+url.PathEscape(id)+"/json", nil, &container); err != nil {
+		return err
+	}
+	if !container.State.Running {
+		return fmt.Errorf("容器 %s 未运行（%s）", shortContainerID(id), container.State.Status)
+	}
+	return nil
+}
+
+// createExec 在容器中创建带 TTY 的 exec 实例，返回 exec ID
+func (c *containerClient) createExec(opts models.ContainerExecOptions, cmd []string, cols, rows uint16) (string, error) {
+
+	body := map[string]interface{}{
+		"AttachStdin":  true,
+		"AttachStdout": true,
+		"AttachStderr": true,
+		"Tty":          true,
+		"Cmd":          cmd,
+		"Env":          []string{"TERM=xterm-256color"},
+		"ConsoleSize":  []uint16{rows, cols},
+	}
+	if opts.User != "" {
+		body["User"] = opts.User
+	}
+	if opts.Cwd != "" {
+		body["WorkingDir"] = opts.Cwd
+	}
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+	path := "/containers/" + url.PathEscape(opts.Container) + "/exec"
+	if err := c.do(http.MethodPost, path, body, &created); err != nil {
+		return "", err
+	}
+	if created.ID == "" {
+		return "", fmt.Errorf("容器引擎未返回 exec ID")
+	}
+	return created.ID, nil
+}
+
+// startExec 启动 exec 实例并接管连接，之后连接上是 TTY 的原始字节流
+//
+// 请求携带 Upgrade: tcp，引擎返回 101（较旧的版本返回 200）后不再使用 HTTP 协议。
+func (c *containerClient) startExec(execID string) (net.Conn, *bufio.Reader, error) {
+
+	req, err := c.newRequest(http.MethodPost, "/exec/"+url.PathEscape(execID)+"/start",
+		map[string]bool{"Detach": false, "Tty": true})
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+
+	ctx, cancel := context.WithTimeout(context.Background(), containerAPITimeout)
+	defer cancel()
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(containerAPITimeout))
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("启动 exec 失败: %v", err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("启动 exec 失败: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols && resp.StatusCode != http.StatusOK {
+		err := containerAPIError(resp)
+		resp.Body.Close()
+		conn.Close()
+		return nil, nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	return conn, reader, nil
+}
+
+// resizeExec 调整 exec TTY 的尺寸
+func (c *containerClient) resizeExec(execID string, rows, cols uint16) error {
+
+	path := fmt.Sprintf("/exec/%s/resize?h=%d&w=%d", url.PathEscape(execID), rows, cols)
+	return c.do(http.MethodPost, path, nil, nil)
+}
+
+// inspectExec 查询 exec 实例是否仍在运行及其退出码
+func (c *containerClient) inspectExec(execID string) (bool, int, error) {
+
+	var state struct {
+		Running  bool `json:"Running"`
+		ExitCode *int `json:"ExitCode"`
+	}
+	if err := c.do(http.MethodGet, "/exec/"+url.PathEscape(execID)+"/json", nil, &state); err != nil {
+		return false, -1, err
+	}
+	if state.ExitCode == nil {
+		return state.Running, -1, nil
+	}
+	return state.Running, *state.ExitCode, nil
+}
+
+// containerExec 容器中带 TTY 的 exec 会话
+//
+// Docker API 没有终止 exec 进程的接口，Close 只断开连接；
+// 引擎随之关闭 TTY，shell 收到 SIGHUP 后通常会退出。
+type containerExec struct {
+	client *containerClient
+	execID string
+	conn   net.Conn
+	reader *bufio.Reader
+
+	exitOnce sync.Once
+	// 输出结束并取得退出码后关闭
+	exited chan struct{}
+	status ExitStatus
+}
+
+// startContainerExec 在容器中启动 exec 会话
+func startContainerExec(client *containerClient, opts models.ContainerExecOptions, cmd []string, cols, rows uint16) (*containerExec, error) {
+
+	// 重启会话时容器可能已经停止，每次启动都要检查
+	if err := client.checkContainer(opts.Container); err != nil {
+		return nil, err
+	}
+	execID, err := client.createExec(opts, cmd, cols, rows)
+	if err != nil {
+		return nil, err
+	}
+	conn, reader, err := client.startExec(execID)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &containerExec{
+		client: client,
+		execID: execID,
+		conn:   conn,
+		reader: reader,
+		exited: make(chan struct{}),
+	}
+	// 旧版本引擎会忽略 ConsoleSize，启动后再同步一次尺寸
+	if err := client.resizeExec(execID, rows, cols); err != nil {
+		log.Printf("调整容器 exec %s 尺寸失败: %v", shortContainerID(execID), err)
+	}
+	return e, nil
+}
+
+// Read 读取 TTY 输出，连接结束后查询退出码
+func (e *containerExec) Read(p []byte) (int, error) {
+
+	n, err := e.reader.Read(p)
+	if err != nil {
+		e.exitOnce.Do(func() { go e.finish() })
+	}
+	return n, err
+}
+
+// finish 等待引擎更新 exec 状态并记录退出码
+func (e *containerExec) finish() {
+
+	e.status = ExitStatus{Code: -1}
+	deadline := time.Now().Add(containerExitWait)
+	for {
+		running, code, err := e.client.inspectExec(e.execID)
+		if err != nil || !running {
+			if err == nil {
+				e.status.Code = code
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	close(e.exited)
+}
+
+func (e *containerExec) Write(p []byte) (int, error) {
+	return e.conn.Write(p)
+}
+
+// Resize 调整 exec TTY 的尺寸
+func (e *containerExec) Resize(rows, cols uint16) error {
+	return e.client.resizeExec(e.execID, rows, cols)
+}
+
+// Close 断开 exec 连接
+func (e *containerExec) Close() error {
+	return e.conn.Close()
+}
+
+// Wait 等待 exec 结束
+func (e *containerExec) Wait() ExitStatus {
+
+	<-e.exited
+	return e.status
+}
+
+// Process exec 进程运行在容器中，没有本地子进程
+func (e *containerExec) Process() *exec.Cmd {
+	return nil
+}
+
+// shortContainerID 完整的 64 位容器或 exec ID 截短为 12 位，容器名称原样返回
+func shortContainerID(id string) string {
+
+	if len(id) == 64 && strings.Trim(id, "0123456789abcdef") == "" {
+		return id[:12]
+	}
+	return id
+}
+
+// containerEngine 按设置创建容器引擎 API 客户端
+func (m *Manager) containerEngine() (*containerClient, error) {
+
+	configured := ""
+	if m.settings != nil {
+		configured = m.settings.ContainerSocket
+	}
+	socket, err := resolveContainerSocket(configured)
+	if err != nil {
+		return nil, err
+	}
+	return newContainerClient(socket), nil
+}
+
+// ListContainers 列出正在运行的容器
+func (m *Manager) ListContainers() ([]models.Container, error) {
+
+	client, err := m.containerEngine()
+	if err != nil {
+		return nil, err
+	}
+	return client.listContainers()
+}
+
+// CreateContainerSession 在运行中的容器内创建 exec 终端会话
+//
+// exec 结束后按 Enter 会以相同命令重新创建 exec。
+func (m *Manager) CreateContainerSession(opts models.ContainerExecOptions) (*models.TerminalSessionInfo, error) {
+
+	if err := m.websocketManager.sessions.checkLimit(); err != nil {
+		return nil, err
+	}
+
+	opts.Container = strings.TrimSpace(opts.Container)
+	if opts.Container == "" {
+		return nil, fmt.Errorf("容器不能为空")
+	}
+	client, err := m.containerEngine()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := containerDefaultCommand
+	if fields := strings.Fields(opts.Command); len(fields) > 0 {
+		cmd = fields
+	}
+
+	title := opts.Title
+	if title == "" {
+		title = shortContainerID(opts.Container)
+	}
+
+	session := m.newSession(newSessionID(), title)
+	session.Kind = SessionKindContainer
+	session.shellDesc = shortContainerID(opts.Container)
+	if opts.Command != "" {
+		session.shellDesc += " " + opts.Command
+	}
+	session.cols, session.rows = 120, 20
+	session.launch = func(cols, rows uint16) (sessionBackend, error) {
+		backend, err := startContainerExec(client, opts, cmd, cols, rows)
+		if err != nil {
+			return nil, err
+		}
+		return backend, nil
+	}
+
+	err = session.spawn()
+	if err != nil {
+		return nil, err
+	}
+	m.startSession(session)
+
+	if err := m.websocketManager.sessions.add(session); err != nil {
+		session.close()
+		return nil, err
+	}
+
+	log.Printf("已创建容器会话 %s (%s)", session.ID, session.shellDesc)
+	info := session.info()
+	return &info, nil
+}
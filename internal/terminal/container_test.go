@@ -0,0 +1,330 @@
+package terminal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"edex-ui-golang/internal/models"
+)
+
+// fakeExec 测试引擎中的 exec 实例
+type fakeExec struct {
+	container   string
+	consoleSize []uint16
+	running     bool
+	exitCode    int
+}
+
+// fakeEngine 模拟 Docker API 的最小子集，监听 unix socket
+//
+// exec 的 TTY 回显输入，收到 exit 时以退出码 7 结束。
+type fakeEngine struct {
+	socket string
+
+	mu         sync.Mutex
+	containers map[string]string // 容器 ID -> 状态
+	execs      map[string]*fakeExec
+	resizes    [][2]int // 每次 resize 的列数与行数
+	upgrades   []string // /start 请求的 Upgrade 头
+}
+
+func newFakeEngine(t *testing.T) *fakeEngine {
+
+	t.Helper()
+
+	engine := &fakeEngine{
+		socket:     filepath.Join(t.TempDir(), "engine.sock"),
+		containers: map[string]string{"web": "running", "db": "exited"},
+		execs:      make(map[string]*fakeExec),
+	}
+	listener, err := net.Listen("unix", engine.socket)
+	if err != nil {
+		t.Skipf("unix socket unavailable: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /containers/{id}/json", engine.inspectContainer)
+	mux.HandleFunc("POST /containers/{id}/exec", engine.createExec)
+	mux.HandleFunc("POST /exec/{id}/start", engine.startExec)
+	mux.HandleFunc("POST /exec/{id}/resize", engine.resizeExec)
+	mux.HandleFunc("GET /exec/{id}/json", engine.inspectExec)
+
+	server := httptest.NewUnstartedServer(mux)
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return engine
+}
+
+// apiError 以引擎的格式返回错误
+func apiError(w http.ResponseWriter, status int, message string) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+func (e *fakeEngine) inspectContainer(w http.ResponseWriter, r *http.Request) {
+
+	e.mu.Lock()
+	status, ok := e.containers[r.PathValue("id")]
+	e.mu.Unlock()
+
+	if !ok {
+		apiError(w, http.StatusNotFound, "No such container: "+r.PathValue("id"))
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"State": map[string]interface{}{"Running": status == "running", "Status": status},
+	})
+}
+
+func (e *fakeEngine) createExec(w http.ResponseWriter, r *http.Request) {
+
+	var body struct {
+		Tty         bool
+		Cmd         []string
+		ConsoleSize []uint16
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !body.Tty || len(body.Cmd) == 0 {
+		apiError(w, http.StatusBadRequest, "invalid exec config")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	id := r.PathValue("id")
+	if e.containers[id] != "running" {
+		apiError(w, http.StatusConflict, "container "+id+" is not running")
+		return
+	}
+	execID := fmt.Sprintf("exec%d", len(e.execs)+1)
+	e.execs[execID] = &fakeExec{container: id, consoleSize: body.ConsoleSize}
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]string{"Id": execID})
+}
+
+func (e *fakeEngine) startExec(w http.ResponseWriter, r *http.Request) {
+
+	e.mu.Lock()
+	ex, ok := e.execs[r.PathValue("id")]
+	e.upgrades = append(e.upgrades, r.Header.Get("Upgrade"))
+	e.mu.Unlock()
+	if !ok {
+		apiError(w, http.StatusNotFound, "No such exec instance")
+		return
+	}
+	_, _ = io.Copy(io.Discard, r.Body)
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	e.mu.Lock()
+	ex.running = true
+	e.mu.Unlock()
+
+	_, _ = rw.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+	_, _ = rw.WriteString("ready\r\n")
+	_ = rw.Flush()
+
+	scanner := bufio.NewScanner(rw)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "exit" {
+			e.mu.Lock()
+			ex.running, ex.exitCode = false, 7
+			e.mu.Unlock()
+			return
+		}
+		_, _ = rw.WriteString("echo:" + line + "\r\n")
+		_ = rw.Flush()
+	}
+
+	// 客户端断开连接，shell 因 SIGHUP 退出
+	e.mu.Lock()
+	ex.running, ex.exitCode = false, 129
+	e.mu.Unlock()
+}
+
+func (e *fakeEngine) resizeExec(w http.ResponseWriter, r *http.Request) {
+
+	var cols, rows int
+	if _, err := fmt.Sscanf(r.URL.Query().Get("w")+" "+r.URL.Query().Get("h"), "%d %d", &cols, &rows); err != nil {
+		apiError(w, http.StatusBadRequest, "invalid size")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.execs[r.PathValue("id")]; !ok {
+		apiError(w, http.StatusNotFound, "No such exec instance")
+		return
+	}
+	e.resizes = append(e.resizes, [2]int{cols, rows})
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (e *fakeEngine) inspectExec(w http.ResponseWriter, r *http.Request) {
+
+	e.mu.Lock()
+	ex, ok := e.execs[r.PathValue("id")]
+	var state map[string]interface{}
+	if ok {
+		state = map[string]interface{}{"Running": ex.running, "ExitCode": ex.exitCode}
+	}
+	e.mu.Unlock()
+
+	if !ok {
+		apiError(w, http.StatusNotFound, "No such exec instance")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(state)
+}
+
+// lastResize 返回最近一次 resize 请求
+func (e *fakeEngine) lastResize() [2]int {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.resizes) == 0 {
+		return [2]int{}
+	}
+	return e.resizes[len(e.resizes)-1]
+}
+
+func TestContainerExecIO(t *testing.T) {
+
+	engine := newFakeEngine(t)
+	client := newContainerClient(engine.socket)
+
+	e, err := startContainerExec(client, models.ContainerExecOptions{Container: "web"}, []string{"sh"}, 100, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	engine.mu.Lock()
+	if got := engine.upgrades; len(got) != 1 || got[0] != "tcp" {
+		t.Errorf("Upgrade headers = %q, want [tcp]", got)
+	}
+	if got := engine.execs[e.execID].consoleSize; len(got) != 2 || got[0] != 30 || got[1] != 100 {
+		t.Errorf("ConsoleSize = %v, want [30 100]", got)
+	}
+	engine.mu.Unlock()
+	// 启动后同步一次尺寸，兼容忽略 ConsoleSize 的旧版本引擎
+	if got := engine.lastResize(); got != [2]int{100, 30} {
+		t.Errorf("initial resize = %v, want [100 30]", got)
+	}
+
+	out := collectOutput(e)
+	out.waitFor(t, "ready", 5*time.Second)
+	if _, err := e.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	out.waitFor(t, "echo:hello", 5*time.Second)
+
+	if err := e.Resize(40, 132); err != nil {
+		t.Fatal(err)
+	}
+	if got := engine.lastResize(); got != [2]int{132, 40} {
+		t.Errorf("resize = %v, want [132 40]", got)
+	}
+
+	if _, err := e.Write([]byte("exit\n")); err != nil {
+		t.Fatal(err)
+	}
+	if status := waitStatus(t, e, 5*time.Second); status != (ExitStatus{Code: 7}) {
+		t.Errorf("status = %+v, want code 7", status)
+	}
+}
+
+func TestContainerExecRejectsUnavailableContainer(t *testing.T) {
+
+	engine := newFakeEngine(t)
+	client := newContainerClient(engine.socket)
+
+	tests := []struct {
+		container string
+		want      string
+	}{
+		{"db", "未运行"},
+		{"missing", "No such container"},
+	}
+	for _, tt := range tests {
+		_, err := startContainerExec(client, models.ContainerExecOptions{Container: tt.container}, []string{"sh"}, 80, 24)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.container, err, tt.want)
+		}
+	}
+
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	if len(engine.execs) != 0 {
+		t.Errorf("created %d exec instances for unavailable containers", len(engine.execs))
+	}
+}
+
+func TestContainerSession(t *testing.T) {
+
+	engine := newFakeEngine(t)
+	m := NewManager(&models.Settings{ContainerSocket: "unix://" + engine.socket})
+	defer m.Close()
+
+	info, err := m.CreateContainerSession(models.ContainerExecOptions{Container: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := m.getSession(info.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 尺寸调整经会话转发到引擎
+	if err := session.resize(50, 160); err != nil {
+		t.Fatal(err)
+	}
+	if got := engine.lastResize(); got != [2]int{160, 50} {
+		t.Errorf("resize = %v, want [160 50]", got)
+	}
+
+	session.write([]byte("exit\n"))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		session.mu.RLock()
+		status := session.exitStatus
+		session.mu.RUnlock()
+		if status != nil {
+			if status.Code != 7 {
+				t.Errorf("exit status = %+v, want code 7", *status)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("session did not record exit status")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// 容器停止后按 Enter 重启会得到明确的错误
+	engine.mu.Lock()
+	engine.containers["web"] = "exited"
+	engine.mu.Unlock()
+	if err := session.restart(); err == nil || !strings.Contains(err.Error(), "未运行") {
+		t.Errorf("restart after container stopped: %v", err)
+	}
+}
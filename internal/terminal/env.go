@@ -0,0 +1,84 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"edex-ui-golang/internal/utils"
+)
+
+// envKeyPattern 合法的环境变量名
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ParseEnv 解析设置中的额外环境变量
+//
+// 每项形如 KEY=VALUE，项之间以换行或分号分隔；空项与以 # 开头的行被忽略，
+// 值两侧成对的单引号或双引号会被去掉。
+func ParseEnv(spec string) (map[string]string, error) {
+
+	env := make(map[string]string)
+
+	items := strings.FieldsFunc(spec, func(r rune) bool {
+		return r == '\n' || r == ';'
+	})
+	for i, item := range items {
+		item = strings.TrimSpace(strings.TrimSuffix(item, "\r"))
+		if item == "" || strings.HasPrefix(item, "#") {
+			continue
+		}
+
+		key, value, found := utils.Cut(item, "=")
+		key = strings.TrimSpace(key)
+		if !found {
+			return nil, fmt.Errorf("环境变量第 %d 项缺少 '=': %q", i+1, item)
+		}
+		if !envKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("环境变量第 %d 项的名称无效: %q", i+1, key)
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env[key] = value
+	}
+
+	return env, nil
+}
+
+// envList 将环境变量表转换为 key=value 列表，按名称排序
+func envList(env map[string]string) []string {
+
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	list := make([]string, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, key+"="+env[key])
+	}
+	return list
+}
+
+// resolveCwd 解析工作目录：空值使用用户主目录，支持 ~ 开头的路径
+func resolveCwd(cwd string) (string, error) {
+
+	cwd = strings.TrimSpace(cwd)
+	if cwd == "" || cwd == "~" || strings.HasPrefix(cwd, "~/") || strings.HasPrefix(cwd, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("无法获取用户主目录: %v", err)
+		}
+		if len(cwd) > 2 {
+			return filepath.Join(home, cwd[2:]), nil
+		}
+		return home, nil
+	}
+	return cwd, nil
+}
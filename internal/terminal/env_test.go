@@ -0,0 +1,49 @@
+package terminal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEnv(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    map[string]string
+		wantErr string
+	}{
+		{"empty", "", map[string]string{}, ""},
+		{"newline and semicolon separated", "A=1\nB=2;C=3", map[string]string{"A": "1", "B": "2", "C": "3"}, ""},
+		{"CRLF and surrounding spaces", "  A = 1 \r\nB=2\r\n", map[string]string{"A": "1", "B": "2"}, ""},
+		{"double quotes", `GREETING="hello world"`, map[string]string{"GREETING": "hello world"}, ""},
+		{"single quotes", `PS1='$ '`, map[string]string{"PS1": "$ "}, ""},
+		{"unmatched quotes kept", `A="x'` + "\n" + `B="`, map[string]string{"A": `"x'`, "B": `"`}, ""},
+		{"equals inside value", "OPTS=--a=1 --b=2", map[string]string{"OPTS": "--a=1 --b=2"}, ""},
+		{"empty value", "EMPTY=", map[string]string{"EMPTY": ""}, ""},
+		{"duplicate keys keep the last", "A=1\nA=2", map[string]string{"A": "2"}, ""},
+		{"blank lines and comments", "\n# comment\n\n  # indented\nA=1\n;;", map[string]string{"A": "1"}, ""},
+		{"missing equals", "A=1\nJUSTNAME", nil, "第 2 项缺少 '='"},
+		{"empty key", "=value", nil, "名称无效"},
+		{"key starting with digit", "1A=x", nil, "名称无效"},
+		{"key with dash", "MY-VAR=x", nil, "名称无效"},
+		{"key with space", "MY VAR=x", nil, "名称无效"},
+	}
+	for _, tt := range tests {
+		got, err := ParseEnv(tt.spec)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package terminal
+
+// 发送给前端的 Wails 事件名
+const (
+	EventCwdChanged      = "cwd-changed"      // 会话工作目录变化，数据为 models.TerminalCwdChange
+	EventProcessChanged  = "process-changed"  // 会话前台进程变化，数据为 models.TerminalProcessChange
+	EventCommandStarted  = "command-started"  // shell 集成识别到命令开始执行，数据为 models.TerminalCommand
+	EventCommandFinished = "command-finished" // shell 集成识别到命令结束，数据为 models.TerminalCommand
+)
+
+// EventEmitter 向前端发送事件的函数，通常为 Wails runtime.EventsEmit 的封装
+type EventEmitter func(event string, data interface{})
+
+// SetEventEmitter 设置事件发送函数，未设置时事件被丢弃
+func (m *Manager) SetEventEmitter(emit EventEmitter) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.emitter = emit
+}
+
+// emit 向前端发送事件
+func (m *Manager) emit(event string, data interface{}) {
+
+	m.mu.RLock()
+	emit := m.emitter
+	m.mu.RUnlock()
+
+	if emit != nil {
+		emit(event, data)
+	}
+}
@@ -0,0 +1,364 @@
+package terminal
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"edex-ui-golang/internal/models"
+	"edex-ui-golang/internal/utils"
+)
+
+// 导出格式
+const (
+	ExportFormatText = "text" // 去除转义序列的纯文本
+	ExportFormatHTML = "html" // 按主题配色渲染的 HTML
+	ExportFormatANSI = "ansi" // 原始输出，保留转义序列
+)
+
+// defaultPalette xterm.js 默认的 16 色调色板，主题未设置对应颜色时使用
+var defaultPalette = [16]string{
+	"#2e3436", "#cc0000", "#4e9a06", "#c4a000", "#3465a4", "#75507b", "#06989a", "#d3d7cf",
+	"#555753", "#ef2929", "#8ae234", "#fce94f", "#729fcf", "#ad7fa8", "#34e2e2", "#eeeeec",
+}
+
+// exportPalette HTML 导出使用的配色
+type exportPalette struct {
+	foreground string
+	background string
+	fontFamily string
+	ansi       [16]string
+}
+
+// paletteFromTheme 与前端终端相同：主题中设置的颜色优先，其余使用默认调色板
+func paletteFromTheme(theme *models.ThemeInfo) exportPalette {
+
+	palette := exportPalette{foreground: "#ffffff", background: "#000000", ansi: defaultPalette}
+	if theme == nil {
+		return palette
+	}
+
+	if theme.Terminal.Foreground != "" {
+		palette.foreground = theme.Terminal.Foreground
+	}
+	if theme.Terminal.Background != "" {
+		palette.background = theme.Terminal.Background
+	}
+	palette.fontFamily = theme.Terminal.FontFamily
+
+	c := theme.Colors
+	for i, color := range []string{
+		c.Black, c.Red, c.Green, c.Yellow, c.Blue, c.Magenta, c.Cyan, c.White,
+		c.BrightBlack, c.BrightRed, c.BrightGreen, c.BrightYellow, c.BrightBlue, c.BrightMagenta, c.BrightCyan, c.BrightWhite,
+	} {
+		if color != "" {
+			palette.ansi[i] = color
+		}
+	}
+	return palette
+}
+
+// color256 返回 256 色调色板中第 n 个颜色
+func (p exportPalette) color256(n int) string {
+
+	switch {
+	case n < 16:
+		return p.ansi[n]
+	case n < 232:
+		// 6x6x6 色立方
+		levels := [6]int{0, 95, 135, 175, 215, 255}
+		n -= 16
+		return fmt.Sprintf("#%02x%02x%02x", levels[n/36], levels[n/6%6], levels[n%6])
+	default:
+		gray := 8 + (n-232)*10
+		return fmt.Sprintf("#%02x%02x%02x", gray, gray, gray)
+	}
+}
+
+// cellStyle 字符的显示属性，颜色为空表示默认前景或背景色
+type cellStyle struct {
+	fg, bg                                        string
+	bold, dim, italic, underline, inverse, strike bool
+}
+
+// applySGR 按 SGR 参数（CSI ... m）更新显示属性
+func (s *cellStyle) applySGR(params []int, palette exportPalette) {
+
+	if len(params) == 0 {
+		params = []int{0}
+	}
+	for i := 0; i < len(params); i++ {
+		switch p := params[i]; {
+		case p == 0:
+			*s = cellStyle{}
+		case p == 1:
+			s.bold = true
+		case p == 2:
+			s.dim = true
+		case p == 3:
+			s.italic = true
+		case p == 4:
+			s.underline = true
+		case p == 7:
+			s.inverse = true
+		case p == 9:
+			s.strike = true
+		case p == 22:
+			s.bold, s.dim = false, false
+		case p == 23:
+			s.italic = false
+		case p == 24:
+			s.underline = false
+		case p == 27:
+			s.inverse = false
+		case p == 29:
+			s.strike = false
+		case p >= 30 && p <= 37:
+			s.fg = palette.ansi[p-30]
+		case p == 39:
+			s.fg = ""
+		case p >= 40 && p <= 47:
+			s.bg = palette.ansi[p-40]
+		case p == 49:
+			s.bg = ""
+		case p >= 90 && p <= 97:
+			s.fg = palette.ansi[p-90+8]
+		case p >= 100 && p <= 107:
+			s.bg = palette.ansi[p-100+8]
+		case p == 38 || p == 48:
+			// 扩展颜色：38;5;n 或 38;2;r;g;b
+			var color string
+			if i+2 < len(params) && params[i+1] == 5 {
+				color = palette.color256(min(max(params[i+2], 0), 255))
+				i += 2
+			} else if i+4 < len(params) && params[i+1] == 2 {
+				color = fmt.Sprintf("#%02x%02x%02x", params[i+2]&0xff, params[i+3]&0xff, params[i+4]&0xff)
+				i += 4
+			} else {
+				return
+			}
+			if p == 38 {
+				s.fg = color
+			} else {
+				s.bg = color
+			}
+		}
+	}
+}
+
+// css 返回对应的内联样式，默认属性返回空串
+func (s cellStyle) css(palette exportPalette) string {
+
+	fg, bg := s.fg, s.bg
+	if s.inverse {
+		fg, bg = bg, fg
+		if fg == "" {
+			fg = palette.background
+		}
+		if bg == "" {
+			bg = palette.foreground
+		}
+	}
+
+	var rules []string
+	if fg != "" {
+		rules = append(rules, "color:"+fg)
+	}
+	if bg != "" {
+		rules = append(rules, "background:"+bg)
+	}
+	if s.bold {
+		rules = append(rules, "font-weight:bold")
+	}
+	if s.dim {
+		rules = append(rules, "opacity:0.5")
+	}
+	if s.italic {
+		rules = append(rules, "font-style:italic")
+	}
+	switch {
+	case s.underline && s.strike:
+		rules = append(rules, "text-decoration:underline line-through")
+	case s.underline:
+		rules = append(rules, "text-decoration:underline")
+	case s.strike:
+		rules = append(rules, "text-decoration:line-through")
+	}
+	return strings.Join(rules, ";")
+}
+
+// cssValue 去除主题取值中可能破坏样式表的字符
+func cssValue(value string) string {
+
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`"'<>{};\`, r) {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+// parseSGRParams 解析 CSI 参数，空参数与无法解析的参数按 0 处理
+func parseSGRParams(p []byte) []int {
+
+	if len(p) == 0 {
+		return nil
+	}
+	fields := strings.Split(string(p), ";")
+	params := make([]int, len(fields))
+	for i, field := range fields {
+		params[i], _ = strconv.Atoi(field)
+	}
+	return params
+}
+
+// renderHTML 将输出渲染为带配色的 HTML 页面
+//
+// 与 stripANSI 一样处理退格与回车，只解释 SGR 颜色与样式，其余转义序列被忽略。
+func renderHTML(p []byte, title string, palette exportPalette) []byte {
+
+	// 每个字符记录样式下标，退格时连同样式一起删除
+	var runes []rune
+	var styleOf []int
+	styles := []cellStyle{{}}
+	index := map[cellStyle]int{{}: 0}
+	current := 0
+
+	for i := 0; i < len(p); {
+		b := p[i]
+		switch {
+		case b == 0x1b:
+			end := skipEscape(p, i)
+			if i+1 < len(p) && p[i+1] == '[' && p[end] == 'm' {
+				style := styles[current]
+				style.applySGR(parseSGRParams(p[i+2:end]), palette)
+				idx, ok := index[style]
+				if !ok {
+					idx = len(styles)
+					styles = append(styles, style)
+					index[style] = idx
+				}
+				current = idx
+			}
+			i = end + 1
+		case b == '\b':
+			if len(runes) > 0 {
+				runes, styleOf = runes[:len(runes)-1], styleOf[:len(styleOf)-1]
+			}
+			i++
+		case b == '\n' || b == '\t' || (b >= 0x20 && b != 0x7f):
+			r, size := utf8.DecodeRune(p[i:])
+			runes = append(runes, r)
+			styleOf = append(styleOf, current)
+			i += size
+		default:
+			// 其余控制字符（含回车）不可见
+			i++
+		}
+	}
+
+	var body strings.Builder
+	for start := 0; start < len(runes); {
+		end := start
+		for end < len(runes) && styleOf[end] == styleOf[start] {
+			end++
+		}
+		text := html.EscapeString(string(runes[start:end]))
+		if css := styles[styleOf[start]].css(palette); css != "" {
+			body.WriteString(`<span style="` + html.EscapeString(css) + `">` + text + `</span>`)
+		} else {
+			body.WriteString(text)
+		}
+		start = end
+	}
+
+	font := "monospace"
+	if family := cssValue(palette.fontFamily); family != "" {
+		font = `"` + family + `", monospace`
+	}
+
+	var out strings.Builder
+	out.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	out.WriteString("<title>" + html.EscapeString(title) + "</title>\n")
+	fmt.Fprintf(&out, "<style>\nbody { margin: 0; background: %s; }\n", cssValue(palette.background))
+	fmt.Fprintf(&out, "pre { margin: 0; padding: 1em; color: %s; background: %s; font-family: %s; white-space: pre-wrap; word-break: break-all; }\n",
+		cssValue(palette.foreground), cssValue(palette.background), font)
+	out.WriteString("</style>\n</head>\n<body>\n<pre>")
+	out.WriteString(body.String())
+	out.WriteString("</pre>\n</body>\n</html>\n")
+	return []byte(out.String())
+}
+
+// exportFormat 确定导出格式，未指定时按文件扩展名判断
+func exportFormat(format, path string) (string, error) {
+
+	switch strings.ToLower(format) {
+	case ExportFormatText, ExportFormatHTML, ExportFormatANSI:
+		return strings.ToLower(format), nil
+	case "":
+	default:
+		return "", fmt.Errorf("未知的导出格式: %s", format)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		return ExportFormatHTML, nil
+	case ".ans", ".ansi":
+		return ExportFormatANSI, nil
+	default:
+		return ExportFormatText, nil
+	}
+}
+
+// ExportOutput 将会话输出或其中的偏移区间导出到文件
+//
+// theme 为当前主题，仅用于 HTML 导出，为 nil 时使用默认配色。
+func (m *Manager) ExportOutput(sessionID string, opts models.TerminalExport, theme *models.ThemeInfo) (*models.TerminalExportResult, error) {
+
+	session, err := m.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	path := strings.TrimSpace(opts.Path)
+	if path == "" {
+		return nil, fmt.Errorf("导出路径不能为空")
+	}
+	format, err := exportFormat(opts.Format, path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, start := session.scrollback.Range(opts.From, opts.To)
+	var out []byte
+	switch format {
+	case ExportFormatText:
+		out = stripANSI(data)
+	case ExportFormatHTML:
+		title := session.info().Title
+		if title == "" {
+			title = sessionID
+		}
+		out = renderHTML(data, title, paletteFromTheme(theme))
+	default:
+		out = data
+	}
+
+	if err := utils.SafeWriteFile(path, out, 0644); err != nil {
+		return nil, err
+	}
+
+	log.Printf("已导出会话 %s 的输出到 %s (%s)", sessionID, path, format)
+	return &models.TerminalExportResult{
+		SessionID: sessionID,
+		Path:      path,
+		Format:    format,
+		Start:     start,
+		End:       start + int64(len(data)),
+		Size:      len(out),
+	}, nil
+}
@@ -0,0 +1,142 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+
+	"edex-ui-golang/internal/models"
+)
+
+func TestApplySGR(t *testing.T) {
+
+	palette := paletteFromTheme(nil)
+
+	tests := []struct {
+		name   string
+		start  cellStyle
+		params []int
+		want   cellStyle
+	}{
+		{"empty resets", cellStyle{bold: true, fg: "#fff"}, nil, cellStyle{}},
+		{"basic colors", cellStyle{}, []int{1, 31, 42}, cellStyle{bold: true, fg: defaultPalette[1], bg: defaultPalette[2]}},
+		{"bright colors", cellStyle{}, []int{91, 104}, cellStyle{fg: defaultPalette[9], bg: defaultPalette[12]}},
+		{"default colors", cellStyle{fg: "#111", bg: "#222"}, []int{39, 49}, cellStyle{}},
+		{"attributes off", cellStyle{bold: true, dim: true, italic: true, underline: true, inverse: true, strike: true}, []int{22, 23, 24, 27, 29}, cellStyle{}},
+		{"256 color palette", cellStyle{}, []int{38, 5, 3}, cellStyle{fg: defaultPalette[3]}},
+		{"256 color cube", cellStyle{}, []int{38, 5, 196}, cellStyle{fg: "#ff0000"}},
+		{"256 color gray", cellStyle{}, []int{48, 5, 232}, cellStyle{bg: "#080808"}},
+		{"256 color out of range", cellStyle{}, []int{38, 5, 300}, cellStyle{fg: "#eeeeee"}},
+		{"true color", cellStyle{}, []int{38, 2, 1, 2, 3, 1}, cellStyle{fg: "#010203", bold: true}},
+		{"true color background", cellStyle{}, []int{48, 2, 255, 128, 0}, cellStyle{bg: "#ff8000"}},
+		// 被截断的扩展颜色不能越界，也不能把后续参数当作颜色
+		{"truncated 38", cellStyle{}, []int{1, 38}, cellStyle{bold: true}},
+		{"truncated 38;5", cellStyle{}, []int{1, 38, 5}, cellStyle{bold: true}},
+		{"truncated 48;5", cellStyle{fg: "#abc"}, []int{48, 5}, cellStyle{fg: "#abc"}},
+		{"truncated 38;2", cellStyle{}, []int{38, 2, 1, 2}, cellStyle{}},
+		{"unknown color mode", cellStyle{}, []int{38, 7, 1}, cellStyle{}},
+	}
+	for _, tt := range tests {
+		style := tt.start
+		style.applySGR(tt.params, palette)
+		if style != tt.want {
+			t.Errorf("%s: applySGR(%v) = %+v, want %+v", tt.name, tt.params, style, tt.want)
+		}
+	}
+}
+
+func TestCellStyleCSS(t *testing.T) {
+
+	palette := paletteFromTheme(nil)
+
+	tests := []struct {
+		style cellStyle
+		want  string
+	}{
+		{cellStyle{}, ""},
+		{cellStyle{fg: "#123456", bold: true}, "color:#123456;font-weight:bold"},
+		{cellStyle{inverse: true}, "color:#000000;background:#ffffff"},
+		{cellStyle{fg: "#111111", inverse: true}, "color:#000000;background:#111111"},
+		{cellStyle{underline: true, strike: true}, "text-decoration:underline line-through"},
+	}
+	for _, tt := range tests {
+		if got := tt.style.css(palette); got != tt.want {
+			t.Errorf("%+v: css() = %q, want %q", tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestParseSGRParams(t *testing.T) {
+
+	tests := []struct {
+		in   string
+		want []int
+	}{
+		{"", nil},
+		{"1;31", []int{1, 31}},
+		{";5", []int{0, 5}},
+		{"38;x;1", []int{38, 0, 1}},
+	}
+	for _, tt := range tests {
+		got := parseSGRParams([]byte(tt.in))
+		if len(got) != len(tt.want) {
+			t.Errorf("parseSGRParams(%q) = %v, want %v", tt.in, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("parseSGRParams(%q) = %v, want %v", tt.in, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestExportFormat(t *testing.T) {
+
+	tests := []struct {
+		format, path string
+		want         string
+		wantErr      bool
+	}{
+		{"", "out.txt", ExportFormatText, false},
+		{"", "out.HTML", ExportFormatHTML, false},
+		{"", "out.htm", ExportFormatHTML, false},
+		{"", "out.ans", ExportFormatANSI, false},
+		{"", "out", ExportFormatText, false},
+		{"ANSI", "out.html", ExportFormatANSI, false},
+		{"pdf", "out.pdf", "", true},
+	}
+	for _, tt := range tests {
+		got, err := exportFormat(tt.format, tt.path)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("exportFormat(%q, %q) = %q, %v; want %q, error %v", tt.format, tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+
+	theme := &models.ThemeInfo{}
+	theme.Terminal.Foreground = "#eeeeee"
+	theme.Terminal.Background = "#101010"
+	theme.Terminal.FontFamily = `Fira"; } body { x`
+	theme.Colors.Red = "#ff5555"
+
+	out := string(renderHTML([]byte("\x1b[31mred\x1b[0m a<b\r\nab\bc\x1b[38;5"), "<title>", paletteFromTheme(theme)))
+
+	for _, want := range []string{
+		`<span style="color:#ff5555">red</span>`,
+		" a&lt;b\nac",
+		"<title>&lt;title&gt;</title>",
+		"background: #101010;",
+		"color: #eeeeee;",
+		`font-family: "Fira  body  x", monospace;`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "\r") || strings.Contains(out, "\x1b") {
+		t.Errorf("control characters left in output: %q", out)
+	}
+}
@@ -0,0 +1,118 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// limitEvent 会话进程触及资源限制的事件
+type limitEvent struct {
+	code    string
+	message string
+}
+
+// limitReporter 能报告资源限制事件的后端（Linux 上启用了 cgroup 的本地 PTY）
+type limitReporter interface {
+	// limitEvents 返回上次调用以来新发生的事件
+	limitEvents() []limitEvent
+	// limitStatus 返回限制的实现方式（cgroup 或 rlimit）与未能完整应用的原因
+	limitStatus() (string, string)
+}
+
+// sendError 向 v1 客户端发送带错误码的 error 消息
+func (ts *TerminalSession) sendError(code, message string) {
+
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	for client := range ts.clients {
+		client.sendControl(msgError, errorData{Code: code, Message: message})
+	}
+}
+
+// reportLimitEvents 报告后端新发生的资源限制事件
+//
+// banner 为 true 时同时在终端中显示提示，只能在 pump 协程中使用，避免与输出交错。
+func (ts *TerminalSession) reportLimitEvents(backend sessionBackend, banner bool) {
+
+	reporter, ok := backend.(limitReporter)
+	if !ok {
+		return
+	}
+	for _, event := range reporter.limitEvents() {
+		log.Printf("会话 %s: %s", ts.ID, event.message)
+		if banner {
+			ts.broadcast([]byte("\r\n\x1b[0m[" + event.message + "]"))
+		}
+		ts.sendError(event.code, event.message)
+	}
+}
+
+// pollLimits 检查运行中的进程是否触及资源限制
+func (ts *TerminalSession) pollLimits() {
+
+	ts.mu.RLock()
+	backend, running := ts.backend, ts.exitStatus == nil
+	ts.mu.RUnlock()
+
+	if backend != nil && running {
+		ts.reportLimitEvents(backend, false)
+	}
+}
+
+// noteInput 记录最近一次输入的时间
+func (ts *TerminalSession) noteInput(now time.Time) {
+
+	ts.mu.Lock()
+	ts.activity.lastInput = now
+	ts.mu.Unlock()
+}
+
+// pollIdleTimeout 没有输入输出的时间超过空闲超时后结束会话
+func (ts *TerminalSession) pollIdleTimeout(now time.Time) {
+
+	ts.mu.RLock()
+	timeout, onIdle := ts.idleTimeout, ts.onIdle
+	last := ts.CreatedAt
+	if ts.activity.lastInput.After(last) {
+		last = ts.activity.lastInput
+	}
+	if ts.activity.lastOutput.After(last) {
+		last = ts.activity.lastOutput
+	}
+	ts.mu.RUnlock()
+
+	if timeout <= 0 || onIdle == nil || now.Sub(last) < timeout {
+		return
+	}
+
+	message := fmt.Sprintf("会话空闲超过 %s，已结束", timeout)
+	log.Printf("会话 %s: %s", ts.ID, message)
+	ts.broadcast([]byte("\r\n\x1b[0m[" + message + "]\r\n"))
+	ts.sendError(errCodeIdleTimeout, message)
+	onIdle(ts)
+}
+
+// rejectConnection 无法为连接提供会话时告知客户端原因并关闭连接
+func rejectConnection(conn *websocket.Conn, err error) {
+
+	code, closeCode := errCodeSpawnFailed, websocket.CloseInternalServerErr
+	if errors.Is(err, errSessionLimit) {
+		code, closeCode = errCodeSessionLimit, websocket.ClosePolicyViolation
+	}
+
+	deadline := time.Now().Add(clientWriteTimeout)
+	_ = conn.SetWriteDeadline(deadline)
+	if conn.Subprotocol() == protocolV1 {
+		if frame, encodeErr := encodeControl(msgError, errorData{Code: code, Message: err.Error()}); encodeErr == nil {
+			_ = conn.WriteMessage(websocket.TextMessage, frame)
+		}
+	} else {
+		_ = conn.WriteMessage(websocket.BinaryMessage, []byte("\r\n\x1b[0m["+err.Error()+"]\r\n"))
+	}
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, code), deadline)
+}
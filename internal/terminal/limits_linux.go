@@ -0,0 +1,475 @@
+//go:build linux
+
+package terminal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"edex-ui-golang/internal/models"
+	"golang.org/x/sys/unix"
+)
+
+// cgroup 相关参数
+const (
+	// cgroupSessionPrefix 会话 cgroup 的名称前缀
+	cgroupSessionPrefix = "edex-session-"
+	// cgroupCPUPeriod cpu.max 使用的周期（微秒）
+	cgroupCPUPeriod = 100000
+	// cgroupRemoveTimeout 删除会话 cgroup 时等待其中进程退出的最长时间
+	cgroupRemoveTimeout = 2 * time.Second
+)
+
+// rlimit 辅助进程使用的环境变量
+const (
+	rlimitHelperLimitEnv = "EDEX_UI_RLIMIT_AS"   // 内存上限（字节）
+	rlimitHelperExecEnv  = "EDEX_UI_RLIMIT_EXEC" // 设置限制后 exec 的程序
+)
+
+// 资源限制的实现方式，显示在会话信息中
+const (
+	limitModeCgroup = "cgroup"
+	limitModeRlimit = "rlimit"
+)
+
+// cloneIntoCgroupUnsupported 内核不支持 CLONE_INTO_CGROUP（5.7 以下）时置位，之后不再尝试
+var cloneIntoCgroupUnsupported atomic.Bool
+
+func init() {
+
+	if limit, ok := os.LookupEnv(rlimitHelperLimitEnv); ok {
+		runRlimitHelper(limit)
+	}
+}
+
+// runRlimitHelper 作为 rlimit 辅助进程运行：设置 RLIMIT_AS 后 exec 目标程序，不返回
+//
+// 限制在 exec 之前生效，目标程序的第一条指令就受到约束；argv 原样传递，
+// 登录 shell 的 -bash 等程序名得以保留。
+func runRlimitHelper(limit string) {
+
+	path := os.Getenv(rlimitHelperExecEnv)
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, rlimitHelperLimitEnv+"=") && !strings.HasPrefix(kv, rlimitHelperExecEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+
+	bytes, err := strconv.ParseUint(limit, 10, 64)
+	if err == nil {
+		err = unix.Setrlimit(unix.RLIMIT_AS, &unix.Rlimit{Cur: bytes, Max: bytes})
+	}
+	if err == nil {
+		err = syscall.Exec(path, os.Args, env)
+	}
+	fmt.Fprintf(os.Stderr, "启动 %s 失败: %v\r\n", path, err)
+	os.Exit(127)
+}
+
+// processLimits 本地 shell 的资源限制
+//
+// 能使用 cgroup v2 时每个 shell 进程直接创建在独立的子 cgroup 中；
+// 否则经由 rlimit 辅助进程在 exec 前设置 RLIMIT_AS，只能限制内存。
+// 两种方式都在 shell 运行之前生效，启动期间派生的子进程同样受限。
+type processLimits struct {
+	limits models.ShellLimits
+	cgroup string // 会话 cgroup 目录，未使用 cgroup 时为空
+	// cgroup 或 rlimit；note 为未能完整应用限制的原因
+	mode string
+	note string
+	// 为 true 时通过 CLONE_INTO_CGROUP 在 cgroup 中创建进程，否则启动后迁入
+	cloneInto bool
+	// 启动期间打开的 cgroup 目录，未打开时为 -1
+	cgroupFD int
+
+	mu       sync.Mutex
+	released bool
+	// 已报告过的事件计数
+	oomKills int64
+	pidsMax  int64
+}
+
+// newProcessLimits 为即将启动的 shell 准备资源限制，没有限制时返回 nil
+func newProcessLimits(limits models.ShellLimits) *processLimits {
+
+	if limits == (models.ShellLimits{}) {
+		return nil
+	}
+
+	p := &processLimits{limits: limits, cgroupFD: -1}
+	dir, err := createSessionCgroup(limits)
+	if err != nil {
+		log.Printf("无法使用 cgroup 限制 shell 资源，改用 rlimit（仅限制内存）: %v", err)
+		p.mode = limitModeRlimit
+		p.note = fmt.Sprintf("未使用 cgroup：%v；改用 rlimit，仅限制内存", err)
+		if p.limits.MemoryMB <= 0 {
+			p.mode = ""
+			p.note = fmt.Sprintf("未使用 cgroup：%v；rlimit 只能限制内存，CPU 与进程数限制未生效", err)
+		}
+		return p
+	}
+	p.cgroup = dir
+	p.mode = limitModeCgroup
+	p.cloneInto = !cloneIntoCgroupUnsupported.Load()
+	if !p.cloneInto {
+		p.note = "内核不支持在 cgroup 中直接创建进程，shell 启动后才迁入 cgroup"
+	}
+	return p
+}
+
+// prepare 在启动前配置命令，使资源限制在进程运行前生效
+func (p *processLimits) prepare(cmd *exec.Cmd) {
+
+	if p == nil {
+		return
+	}
+
+	switch {
+	case p.cgroup != "" && p.cloneInto:
+		fd, err := unix.Open(p.cgroup, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+		if err != nil {
+			log.Printf("打开 cgroup %s 失败，进程启动后再迁入: %v", p.cgroup, err)
+			p.cloneInto = false
+			p.note = "无法在 cgroup 中直接创建进程，shell 启动后才迁入 cgroup"
+			return
+		}
+		p.closeFD()
+		p.cgroupFD = fd
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = fd
+	case p.cgroup == "" && p.limits.MemoryMB > 0:
+		exe, err := os.Executable()
+		if err != nil {
+			log.Printf("无法定位 rlimit 辅助程序，内存限制未生效: %v", err)
+			p.mode, p.note = "", fmt.Sprintf("内存限制未生效：%v", err)
+			return
+		}
+		env := cmd.Env
+		if env == nil {
+			env = os.Environ()
+		}
+		bytes := uint64(p.limits.MemoryMB) * 1024 * 1024
+		cmd.Env = append(env[:len(env):len(env)],
+			rlimitHelperLimitEnv+"="+strconv.FormatUint(bytes, 10),
+			rlimitHelperExecEnv+"="+cmd.Path)
+		cmd.Path = exe
+	}
+}
+
+// retryWithoutCgroupFD 启动因内核不支持 CLONE_INTO_CGROUP 失败时返回 true
+//
+// 调用方应以新的命令重新启动，之后的进程都改为启动后迁入 cgroup。
+func (p *processLimits) retryWithoutCgroupFD(err error) bool {
+
+	if p == nil || p.cgroupFD < 0 {
+		return false
+	}
+	p.closeFD()
+
+	// 5.3 以下没有 clone3，5.3 至 5.6 不认识 CLONE_INTO_CGROUP 或更长的参数结构
+	if !errors.Is(err, unix.ENOSYS) && !errors.Is(err, unix.EINVAL) && !errors.Is(err, unix.E2BIG) {
+		return false
+	}
+	log.Printf("内核不支持在 cgroup 中直接创建进程，改为启动后迁入: %v", err)
+	cloneIntoCgroupUnsupported.Store(true)
+	p.cloneInto = false
+	p.note = "内核不支持在 cgroup 中直接创建进程，shell 启动后才迁入 cgroup"
+	return true
+}
+
+// started 进程启动后调用；不能直接在 cgroup 中创建进程时在此迁入
+func (p *processLimits) started(pid int) error {
+
+	if p == nil {
+		return nil
+	}
+	p.closeFD()
+
+	if p.cgroup == "" || p.cloneInto {
+		return nil
+	}
+	if err := writeCgroupFile(p.cgroup, "cgroup.procs", strconv.Itoa(pid)); err != nil {
+		p.release()
+		p.mode, p.note = "", fmt.Sprintf("未能将 shell 加入 cgroup，资源限制未生效：%v", err)
+		return fmt.Errorf("将进程 %d 加入 cgroup 失败: %v", pid, err)
+	}
+	return nil
+}
+
+// status 返回资源限制的实现方式与未能完整应用的原因
+func (p *processLimits) status() (string, string) {
+
+	if p == nil {
+		return "", ""
+	}
+	return p.mode, p.note
+}
+
+// closeFD 关闭启动期间打开的 cgroup 目录
+func (p *processLimits) closeFD() {
+
+	if p.cgroupFD >= 0 {
+		_ = unix.Close(p.cgroupFD)
+		p.cgroupFD = -1
+	}
+}
+
+// events 返回上次调用以来新发生的限制事件
+func (p *processLimits) events() []limitEvent {
+
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cgroup == "" || p.released {
+		return nil
+	}
+
+	var events []limitEvent
+	if count := readCgroupCounter(p.cgroup, "memory.events", "oom_kill"); count > p.oomKills {
+		p.oomKills = count
+		events = append(events, limitEvent{
+			code:    errCodeMemoryLimit,
+			message: fmt.Sprintf("进程超出内存限制（%d MiB）被终止", p.limits.MemoryMB),
+		})
+	}
+	if count := readCgroupCounter(p.cgroup, "pids.events", "max"); count > p.pidsMax {
+		p.pidsMax = count
+		events = append(events, limitEvent{
+			code:    errCodeProcessLimit,
+			message: fmt.Sprintf("进程数已达上限（%d 个），无法创建新进程", p.limits.MaxProcesses),
+		})
+	}
+	return events
+}
+
+// release 终止会话 cgroup 中残留的进程并删除 cgroup
+func (p *processLimits) release() {
+
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closeFD()
+	if p.cgroup == "" || p.released {
+		return
+	}
+	p.released = true
+
+	// cgroup.kill 需要 5.14 以上的内核，不支持时 rmdir 会因残留进程失败
+	_ = writeCgroupFile(p.cgroup, "cgroup.kill", "1")
+	deadline := time.Now().Add(cgroupRemoveTimeout)
+	for {
+		err := unix.Rmdir(p.cgroup)
+		if err == nil || errors.Is(err, unix.ENOENT) {
+			return
+		}
+		if !errors.Is(err, unix.EBUSY) || time.Now().After(deadline) {
+			log.Printf("删除 cgroup %s 失败: %v", p.cgroup, err)
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// createSessionCgroup 创建会话 cgroup 并写入限制
+func createSessionCgroup(limits models.ShellLimits) (string, error) {
+
+	var controllers []string
+	if limits.MemoryMB > 0 {
+		controllers = append(controllers, "memory")
+	}
+	if limits.CPUPercent > 0 {
+		controllers = append(controllers, "cpu")
+	}
+	if limits.MaxProcesses > 0 {
+		controllers = append(controllers, "pids")
+	}
+	parent, err := sessionCgroupParent(controllers)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(parent, cgroupSessionPrefix+newSessionID())
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建 cgroup 失败: %v", err)
+	}
+
+	settings := map[string]string{}
+	if limits.MemoryMB > 0 {
+		settings["memory.max"] = strconv.FormatInt(int64(limits.MemoryMB)*1024*1024, 10)
+	}
+	if limits.CPUPercent > 0 {
+		settings["cpu.max"] = fmt.Sprintf("%d %d", limits.CPUPercent*cgroupCPUPeriod/100, cgroupCPUPeriod)
+	}
+	if limits.MaxProcesses > 0 {
+		settings["pids.max"] = strconv.Itoa(limits.MaxProcesses)
+	}
+	for name, value := range settings {
+		if err := writeCgroupFile(dir, name, value); err != nil {
+			_ = unix.Rmdir(dir)
+			return "", err
+		}
+	}
+	if limits.MemoryMB > 0 {
+		// 不允许换出，否则内存限制只是把进程推到 swap 上
+		_ = writeCgroupFile(dir, "memory.swap.max", "0")
+	}
+	return dir, nil
+}
+
+// sessionCgroupParent 返回可以创建会话 cgroup 的目录
+//
+// 程序不会迁移自身，也不会修改 cgroup.subtree_control：只有当前 cgroup（cgroup 命名空间的根）
+// 或其父 cgroup（例如 systemd 以 Delegate= 委派、程序位于其中的叶子 cgroup）
+// 已向子 cgroup 启用全部所需控制器，且当前用户有权在其中创建 cgroup 时才使用。
+func sessionCgroupParent(controllers []string) (string, error) {
+
+	mount, err := cgroup2Mount()
+	if err != nil {
+		return "", err
+	}
+	self, err := selfCgroup(mount)
+	if err != nil {
+		return "", err
+	}
+
+	candidates := []string{self}
+	if parent := filepath.Dir(self); self != mount && strings.HasPrefix(parent, mount) {
+		candidates = append(candidates, parent)
+	}
+
+	var reason error
+	for _, dir := range candidates {
+		err := checkCgroupParent(dir, controllers)
+		if err == nil {
+			return dir, nil
+		}
+		if reason == nil {
+			reason = err
+		}
+	}
+	return "", reason
+}
+
+// selfCgroup 根据 /proc/self/cgroup 计算当前进程所在的 cgroup v2 目录
+func selfCgroup(mount string) (string, error) {
+
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return filepath.Join(mount, path), nil
+		}
+	}
+	return "", fmt.Errorf("当前进程不在 cgroup v2 层级中")
+}
+
+// checkCgroupParent 检查能否在 dir 下创建带有 controllers 控制器的子 cgroup
+func checkCgroupParent(dir string, controllers []string) error {
+
+	if err := unix.Access(dir, unix.W_OK); err != nil {
+		return fmt.Errorf("没有权限在 cgroup %s 中创建子 cgroup", dir)
+	}
+	// 进程迁入子 cgroup 需要对共同祖先的 cgroup.procs 有写权限
+	if err := unix.Access(filepath.Join(dir, "cgroup.procs"), unix.W_OK); err != nil {
+		return fmt.Errorf("没有权限向 cgroup %s 迁入进程", dir)
+	}
+
+	enabled, err := os.ReadFile(filepath.Join(dir, "cgroup.subtree_control"))
+	if err != nil {
+		return err
+	}
+	for _, controller := range controllers {
+		if !containsField(string(enabled), controller) {
+			return fmt.Errorf("cgroup %s 未向子 cgroup 启用 %s 控制器", dir, controller)
+		}
+	}
+	return nil
+}
+
+// cgroup2Mount 查找 cgroup2 文件系统的挂载点
+func cgroup2Mount() (string, error) {
+
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// 挂载点是第 5 列，文件系统类型在 " - " 之后
+		fields := strings.Fields(scanner.Text())
+		for i, field := range fields {
+			if field == "-" && i+1 < len(fields) && fields[i+1] == "cgroup2" && len(fields) > 4 {
+				return fields[4], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("系统未挂载 cgroup v2")
+}
+
+// writeCgroupFile 写入 cgroup 接口文件
+func writeCgroupFile(dir, name, value string) error {
+
+	err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0644)
+	if err != nil {
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		return fmt.Errorf("写入 %s/%s 失败: %w", filepath.Base(dir), name, err)
+	}
+	return nil
+}
+
+// readCgroupCounter 读取 memory.events 等文件中的计数，读取失败时为 0
+func readCgroupCounter(dir, file, key string) int64 {
+
+	data, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, key+" "); ok {
+			count, _ := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			return count
+		}
+	}
+	return 0
+}
+
+// containsField 判断以空白分隔的列表中是否包含 name
+func containsField(list, name string) bool {
+
+	for _, field := range strings.Fields(list) {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
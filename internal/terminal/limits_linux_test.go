@@ -0,0 +1,109 @@
+package terminal
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"edex-ui-golang/internal/models"
+)
+
+func TestRlimitHelperAppliesLimitBeforeExec(t *testing.T) {
+
+	p := &processLimits{limits: models.ShellLimits{MemoryMB: 256}, mode: limitModeRlimit, cgroupFD: -1}
+
+	// 第一条命令就读取限制，说明限制在 exec 之前生效；argv[0] 原样传递
+	cmd := exec.Command("/bin/sh")
+	cmd.Args = []string{"custom-argv0", "-c", `ulimit -v; tr '\0' ' ' < /proc/$$/cmdline; echo; env`}
+	p.prepare(cmd)
+	if filepath.Base(cmd.Path) == "sh" {
+		t.Fatalf("command not wrapped: %s", cmd.Path)
+	}
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	lines := strings.Split(string(out), "\n")
+	if got := strings.TrimSpace(lines[0]); got != "262144" {
+		t.Errorf("ulimit -v = %q, want 262144", got)
+	}
+	if !strings.HasPrefix(lines[1], "custom-argv0 -c ") {
+		t.Errorf("cmdline = %q, want argv[0] preserved", lines[1])
+	}
+	if strings.Contains(string(out), rlimitHelperLimitEnv) || strings.Contains(string(out), rlimitHelperExecEnv) {
+		t.Errorf("helper variables leaked into the environment:\n%s", out)
+	}
+}
+
+func TestCheckCgroupParent(t *testing.T) {
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.procs"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("cpu memory\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		controllers []string
+		wantErr     string
+	}{
+		{[]string{"memory"}, ""},
+		{[]string{"memory", "cpu"}, ""},
+		{[]string{"memory", "pids"}, "pids"},
+	}
+	for _, tt := range tests {
+		err := checkCgroupParent(dir, tt.controllers)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%v: %v", tt.controllers, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%v: error = %v, want mention of %s", tt.controllers, err, tt.wantErr)
+		}
+	}
+
+	// 不会为了启用控制器而改写 subtree_control
+	data, _ := os.ReadFile(filepath.Join(dir, "cgroup.subtree_control"))
+	if string(data) != "cpu memory\n" {
+		t.Errorf("subtree_control modified: %q", data)
+	}
+}
+
+func TestLocalPTYLimitsApplyBeforeShellRuns(t *testing.T) {
+
+	backend, err := startLocalPTY(&spawnSpec{
+		Path:   "/bin/sh",
+		Args:   []string{"sh", "-c", "ulimit -v; cat /proc/self/cgroup"},
+		Cols:   80,
+		Rows:   24,
+		Limits: models.ShellLimits{MemoryMB: 512},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	out := collectOutput(backend)
+	status := waitStatus(t, backend, 10*time.Second)
+	if status.Code != 0 {
+		t.Fatalf("exit status = %+v", status)
+	}
+
+	mode, note := backend.limitStatus()
+	switch mode {
+	case limitModeCgroup:
+		out.waitFor(t, filepath.Base(backend.limits.cgroup), time.Second)
+	case limitModeRlimit:
+		out.waitFor(t, "524288", time.Second)
+		if note == "" {
+			t.Error("rlimit fallback without a reason in the status")
+		}
+	default:
+		t.Fatalf("limits not applied: mode %q, note %q", mode, note)
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !linux
+
+package terminal
+
+import (
+	"log"
+	"os/exec"
+
+	"edex-ui-golang/internal/models"
+)
+
+// processLimits 当前平台不支持限制 shell 资源
+type processLimits struct{}
+
+// newProcessLimits 当前平台忽略资源限制
+func newProcessLimits(limits models.ShellLimits) *processLimits {
+
+	if limits != (models.ShellLimits{}) {
+		log.Printf("shell 资源限制仅在 Linux 上生效，已忽略")
+	}
+	return nil
+}
+
+func (p *processLimits) prepare(cmd *exec.Cmd) {}
+
+func (p *processLimits) retryWithoutCgroupFD(err error) bool { return false }
+
+func (p *processLimits) started(pid int) error { return nil }
+
+func (p *processLimits) status() (string, string) { return "", "" }
+
+func (p *processLimits) events() []limitEvent { return nil }
+
+func (p *processLimits) release() {}
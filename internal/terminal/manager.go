@@ -6,8 +6,10 @@ import (
 	"log"
 	"os"
 	"os/exec"
-	"strconv"
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"edex-ui-golang/internal/models"
 	"edex-ui-golang/internal/utils"
@@ -17,11 +19,20 @@ import (
 type Manager struct {
 	settings         *models.Settings
 	terminal         *models.Terminal
-	extraTerminals   map[int]*models.Terminal
 	websocketManager *WebSocketManager
-	mu               sync.RWMutex
-	ctx              context.Context
-	cancel           context.CancelFunc
+	emitter          EventEmitter
+	// 每次启动随机生成的 WebSocket 连接令牌
+	token string
+	// 长时间命令提醒配置与前端当前显示的会话
+	notify        notifyConfig
+	activeSession string
+	// 当前生效的输出触发规则
+	triggers []compiledTrigger
+	// 已校验的 shell 配置
+	shellProfiles models.ShellProfiles
+	mu            sync.RWMutex
+	ctx           context.Context
+	cancel        context.CancelFunc
 }
 
 // NewManager 创建新的终端管理器
@@ -29,14 +40,17 @@ func NewManager(settings *models.Settings) *Manager {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	manager := &Manager{
-		settings:       settings,
-		extraTerminals: make(map[int]*models.Terminal),
-		ctx:            ctx,
-		cancel:         cancel,
+		settings: settings,
+		notify:   notifyConfigFromSettings(settings),
+		ctx:      ctx,
+		cancel:   cancel,
 	}
 
 	// 创建WebSocket管理器
 	manager.websocketManager = NewWebSocketManager(manager)
+	if settings != nil {
+		manager.websocketManager.sessions.setLimit(settings.MaxSessions)
+	}
 
 	return manager
 }
@@ -48,33 +62,71 @@ func (m *Manager) InitializeTerminal() error {
 		return fmt.Errorf("设置未加载")
 	}
 
-	// 解析shell路径
-	shellPath, err := exec.LookPath(m.settings.Shell)
+	// 解析默认 shell 配置
+	profile, err := m.shellProfile("")
 	if err != nil {
-		return fmt.Errorf("找不到shell: %s", m.settings.Shell)
+		return err
 	}
 
-	log.Printf("找到shell: %s", shellPath)
+	log.Printf("默认 shell 配置: %s (%s)", profile.Name, profile.Path)
 
 	// 检查工作目录
-	if _, err := os.Stat(m.settings.Cwd); os.IsNotExist(err) {
-		return fmt.Errorf("配置的工作目录不存在: %s", m.settings.Cwd)
+	cwd, err := resolveCwd(m.settings.Cwd)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(cwd); os.IsNotExist(err) {
+		return fmt.Errorf("配置的工作目录不存在: %s", cwd)
+	} else if err != nil {
+		return fmt.Errorf("无法访问工作目录 %s: %v", cwd, err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("配置的工作目录不是目录: %s", cwd)
+	}
+
+	// 解析用户定义的额外环境变量
+	env := m.getCleanEnv()
+	extraEnv, err := ParseEnv(m.settings.Env)
+	if err != nil {
+		return fmt.Errorf("环境变量设置无效: %v", err)
+	}
+	for key, value := range extraEnv {
+		env[key] = value
 	}
 
 	// 创建终端
 	terminal := &models.Terminal{
 		Role:   "server",
-		Shell:  shellPath,
-		Params: m.settings.ShellArgs,
-		Cwd:    m.settings.Cwd,
-		Env:    m.getCleanEnv(),
+		Shell:  profile.Path,
+		Params: profile.Args,
+		Login:  profile.Login,
+		Cwd:    cwd,
+		Env:    env,
 		Port:   m.settings.Port,
 	}
 
 	m.terminal = terminal
 
-	// 启动WebSocket服务器
-	if err := m.websocketManager.StartWebSocketServer(m.settings.Port); err != nil {
+	// 释放 shell 集成脚本，保持与程序版本一致
+	if dir, err := installShellIntegration(); err != nil {
+		log.Printf("释放 shell 集成脚本失败: %v", err)
+	} else {
+		log.Printf("shell 集成脚本位于: %s", dir)
+	}
+
+	// 生成本次启动的连接令牌
+	token, err := newAuthToken()
+	if err != nil {
+		return err
+	}
+	m.token = token
+
+	// 启动WebSocket服务器，远程访问需要显式开启
+	host := loopbackHost
+	if m.settings.AllowRemoteTerminal {
+		host = "0.0.0.0"
+		log.Printf("警告: 已开启终端远程访问，WebSocket 服务器将监听所有网卡（端口 %d），任何持有令牌的主机都可以打开 shell", m.settings.Port)
+	}
+	if err := m.websocketManager.StartWebSocketServer(host, m.settings.Port); err != nil {
 		return fmt.Errorf("启动WebSocket服务器失败: %v", err)
 	}
 
@@ -103,48 +155,459 @@ func (m *Manager) getCleanEnv() map[string]string {
 	return env
 }
 
-func (m *Manager) SpawnTTY() (string, error) {
+// newLocalSession 创建本地 shell 会话并启动进程
+//
+// opts 指定了 shell 时直接使用，否则使用 opts.Profile 对应的 shell 配置（为空时为默认配置）；
+// 未指定的工作目录依次沿用 shell 配置与设置中的工作目录。
+func (m *Manager) newLocalSession(sessionID string, opts models.SessionOptions) (*TerminalSession, error) {
 
-	// 查找可用的端口
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	if m.terminal == nil {
+		return nil, fmt.Errorf("终端未初始化")
+	}
 
-	var port int
-	for i := 0; i < 4; i++ {
-		checkPort := m.settings.Port + 2 + i
-		if _, exists := m.extraTerminals[checkPort]; !exists {
-			port = checkPort
-			break
+	terminal := *m.terminal
+	profileID := ""
+	if opts.Shell != "" {
+		shellPath, err := exec.LookPath(opts.Shell)
+		if err != nil {
+			return nil, fmt.Errorf("找不到shell: %s", opts.Shell)
+		}
+		terminal.Shell = shellPath
+		terminal.Params = opts.Args
+		terminal.Login = false
+		terminal.Limits = models.ShellLimits{}
+		terminal.IdleTimeout = 0
+	} else {
+		profile, err := m.shellProfile(opts.Profile)
+		if err != nil {
+			return nil, err
+		}
+		profileID = profile.ID
+		terminal.Shell = profile.Path
+		terminal.Params = profile.Args
+		terminal.Login = profile.Login
+		terminal.Limits = profile.Limits
+		terminal.IdleTimeout = profile.IdleTimeout
+		if opts.Cwd == "" {
+			opts.Cwd = profile.Cwd
+		}
+		if len(profile.Env) > 0 {
+			env := make(map[string]string, len(terminal.Env)+len(profile.Env))
+			for key, value := range terminal.Env {
+				env[key] = value
+			}
+			for key, value := range profile.Env {
+				env[key] = value
+			}
+			terminal.Env = env
 		}
 	}
+	if opts.Cwd != "" {
+		cwd, err := resolveCwd(opts.Cwd)
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(cwd); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("工作目录不存在: %s", cwd)
+		}
+		terminal.Cwd = cwd
+	}
 
-	if port == 0 {
-		return "", fmt.Errorf("错误: 已达到最大TTY数量")
+	session := m.newSession(sessionID, opts.Title)
+	session.profileID = profileID
+	session.idleTimeout = time.Duration(terminal.IdleTimeout) * time.Second
+	if err := session.startProcess(&terminal); err != nil {
+		return nil, err
 	}
+	m.startSession(session)
+	return session, nil
+}
 
-	// 创建新终端
-	terminal := &models.Terminal{
-		Role:   "server",
-		Shell:  m.terminal.Shell,
-		Params: m.terminal.Params,
-		Cwd:    m.terminal.Cwd,
-		Env:    m.terminal.Env,
-		Port:   port,
+// newSession 创建会话对象并接入事件、命令提醒与触发规则
+func (m *Manager) newSession(sessionID, title string) *TerminalSession {
+
+	session := newTerminalSession(sessionID, m.scrollbackSize())
+	session.Title = title
+	session.emit = m.emit
+	session.onCompletion = m.commandCompleted
+	session.triggers = newTriggerRunner()
+	session.triggerRules = m.activeTriggers
+	session.onIdle = m.websocketManager.removeSession
+	return session
+}
+
+// SetMaxSessions 修改会话数量上限，已存在的会话不受影响
+func (m *Manager) SetMaxSessions(limit int) {
+	m.websocketManager.sessions.setLimit(limit)
+}
+
+// startSession 后端启动后开始跟踪会话状态
+func (m *Manager) startSession(session *TerminalSession) {
+
+	go session.track()
+	go session.runTriggers()
+}
+
+// CreateSession 创建新的终端会话，前端通过 /webterminal?session=<id> 附加
+func (m *Manager) CreateSession(opts models.SessionOptions) (*models.TerminalSessionInfo, error) {
+
+	if err := m.websocketManager.sessions.checkLimit(); err != nil {
+		return nil, err
 	}
 
-	m.extraTerminals[port] = terminal
-	log.Printf("新终端后端已在端口 %d 初始化", port)
+	session, err := m.newLocalSession(newSessionID(), opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.websocketManager.sessions.add(session); err != nil {
+		session.close()
+		return nil, err
+	}
 
-	return strconv.Itoa(port), nil
+	log.Printf("已创建终端会话 %s (%s)", session.ID, session.shellDesc)
+	info := session.info()
+	return &info, nil
 }
 
-func (m *Manager) Close() error {
-	// 停止WebSocket服务器
-	m.cancel()
+// CreateSSHSession 使用内置 SSH 客户端创建远程会话
+func (m *Manager) CreateSSHSession(profile models.SSHProfile, opts models.SessionOptions) (*models.TerminalSessionInfo, error) {
+
+	if err := m.websocketManager.sessions.checkLimit(); err != nil {
+		return nil, err
+	}
 
-	if m.websocketManager != nil {
-		return m.websocketManager.StopWebSocketServer()
+	title := opts.Title
+	if title == "" {
+		title = profile.Name
+	}
+	if title == "" {
+		title = profile.User + "@" + profile.Host
 	}
 
+	session := m.newSession(newSessionID(), title)
+	session.Kind = SessionKindSSH
+	session.shellDesc = profile.User + "@" + sshAddress(profile)
+	session.cols, session.rows = 120, 20
+	session.launch = func(cols, rows uint16) (sessionBackend, error) {
+		backend, err := startSSH(profile, cols, rows)
+		if err != nil {
+			return nil, err
+		}
+		return backend, nil
+	}
+
+	err := session.spawn()
+	if err != nil {
+		return nil, err
+	}
+	m.startSession(session)
+
+	if err := m.websocketManager.sessions.add(session); err != nil {
+		session.close()
+		return nil, err
+	}
+
+	log.Printf("已创建 SSH 会话 %s (%s)", session.ID, session.shellDesc)
+	info := session.info()
+	return &info, nil
+}
+
+// ListSessions 列出所有终端会话
+func (m *Manager) ListSessions() []models.TerminalSessionInfo {
+
+	sessions := m.websocketManager.sessions.List()
+	infos := make([]models.TerminalSessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		infos = append(infos, session.info())
+	}
+	return infos
+}
+
+// CloseSession 关闭终端会话并终止其进程
+func (m *Manager) CloseSession(sessionID string) error {
+
+	session, err := m.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+	m.websocketManager.removeSession(session)
 	return nil
 }
+
+// RenameSession 修改会话标题并通知已附加的客户端
+func (m *Manager) RenameSession(sessionID, title string) error {
+
+	session, err := m.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+	session.setTitle(strings.TrimSpace(title))
+	return nil
+}
+
+// SignalSession 向会话的前台进程组或整个会话发送信号
+func (m *Manager) SignalSession(sessionID, signal, target string) (*models.SignalResult, error) {
+
+	session, err := m.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session.signal(signal, target)
+}
+
+// allowedOrigins 返回允许连接 WebSocket 的 Origin 列表
+func (m *Manager) allowedOrigins() []string {
+
+	origins := append([]string(nil), defaultAllowedOrigins...)
+	if m.settings != nil {
+		origins = append(origins, m.settings.TerminalAllowedOrigins...)
+	}
+	return origins
+}
+
+// ConnectionInfo 返回前端连接终端 WebSocket 所需的信息
+func (m *Manager) ConnectionInfo() (*models.TerminalConnection, error) {
+
+	if m.terminal == nil || m.token == "" {
+		return nil, fmt.Errorf("终端未初始化")
+	}
+
+	return &models.TerminalConnection{
+		Host:  loopbackHost,
+		Port:  m.terminal.Port,
+		Path:  "/webterminal",
+		Token: m.token,
+	}, nil
+}
+
+// scrollbackSize 返回配置的会话输出历史大小
+func (m *Manager) scrollbackSize() int {
+
+	if m.settings == nil || m.settings.ScrollbackSize <= 0 {
+		return DefaultScrollbackSize
+	}
+	return m.settings.ScrollbackSize
+}
+
+// getSession 按 ID 获取会话
+func (m *Manager) getSession(sessionID string) (*TerminalSession, error) {
+
+	session, exists := m.websocketManager.GetSession(sessionID)
+	if !exists {
+		return nil, fmt.Errorf("会话不存在: %s", sessionID)
+	}
+	return session, nil
+}
+
+// ShareSession 为会话生成只读分享令牌
+func (m *Manager) ShareSession(sessionID string) (*models.TerminalShare, error) {
+
+	return m.websocketManager.ShareSession(sessionID)
+}
+
+// ListShares 列出会话的分享令牌
+func (m *Manager) ListShares(sessionID string) []models.TerminalShare {
+
+	return m.websocketManager.ListShares(sessionID)
+}
+
+// RevokeShare 撤销分享令牌并断开相关观察者
+func (m *Manager) RevokeShare(token string) error {
+
+	return m.websocketManager.RevokeShare(token)
+}
+
+// ListViewers 列出以只读方式附加到会话的观察者
+func (m *Manager) ListViewers(sessionID string) ([]models.TerminalViewer, error) {
+
+	session, err := m.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session.viewers(), nil
+}
+
+// AddToBroadcast 将会话加入广播输入分组
+func (m *Manager) AddToBroadcast(sessionID string) error {
+
+	return m.websocketManager.AddToBroadcast(sessionID)
+}
+
+// RemoveFromBroadcast 将会话移出广播输入分组
+func (m *Manager) RemoveFromBroadcast(sessionID string) {
+
+	m.websocketManager.RemoveFromBroadcast(sessionID)
+}
+
+// SetBroadcast 开启或关闭广播输入
+func (m *Manager) SetBroadcast(enabled bool) {
+
+	m.websocketManager.SetBroadcast(enabled)
+}
+
+// BroadcastState 返回广播输入分组状态
+func (m *Manager) BroadcastState() models.BroadcastState {
+
+	return m.websocketManager.BroadcastState()
+}
+
+// GetOutputRange 获取会话输出中偏移区间 [from, to) 的内容，to 小于等于 0 表示直到末尾
+func (m *Manager) GetOutputRange(sessionID string, from, to int64) (*models.TerminalOutput, error) {
+
+	session, err := m.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, start := session.scrollback.Range(from, to)
+	first, _ := session.scrollback.Window()
+	return &models.TerminalOutput{
+		SessionID: sessionID,
+		Start:     start,
+		End:       start + int64(len(data)),
+		First:     first,
+		Data:      string(data),
+	}, nil
+}
+
+// GetLastLines 获取会话输出的最后若干行
+func (m *Manager) GetLastLines(sessionID string, lines int) (*models.TerminalOutput, error) {
+
+	session, err := m.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, start := session.scrollback.LastLines(lines)
+	first, _ := session.scrollback.Window()
+	return &models.TerminalOutput{
+		SessionID: sessionID,
+		Start:     start,
+		End:       start + int64(len(data)),
+		First:     first,
+		Data:      string(data),
+	}, nil
+}
+
+// RestartSession 在同一会话中以相同的 shell、参数、工作目录与环境重启已退出的进程
+func (m *Manager) RestartSession(sessionID string) error {
+
+	session, err := m.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+	return session.restart()
+}
+
+// StartRecording 开始以 asciicast v2 格式录制会话，返回录像文件路径
+func (m *Manager) StartRecording(sessionID string) (string, error) {
+
+	session, err := m.getSession(sessionID)
+	if err != nil {
+		return "", err
+	}
+	if session.playback != nil {
+		return "", fmt.Errorf("回放会话不能录制: %s", sessionID)
+	}
+
+	dir, err := recordingsDir()
+	if err != nil {
+		return "", fmt.Errorf("创建录像目录失败: %v", err)
+	}
+
+	name := fmt.Sprintf("%s-%s%s", sessionID, time.Now().Format("20060102-150405"), recordingExt)
+	path := filepath.Join(dir, name)
+
+	env := map[string]string{}
+	if m.terminal != nil {
+		env["SHELL"] = m.terminal.Shell
+		env["TERM"] = m.terminal.Env["TERM"]
+	}
+
+	if err := session.startRecording(path, env); err != nil {
+		return "", err
+	}
+
+	log.Printf("会话 %s 开始录制: %s", sessionID, path)
+	return path, nil
+}
+
+// StopRecording 停止录制会话，返回录像文件路径
+func (m *Manager) StopRecording(sessionID string) (string, error) {
+
+	session, err := m.getSession(sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := session.stopRecording()
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("会话 %s 停止录制: %s", sessionID, path)
+	return path, nil
+}
+
+// ListRecordings 列出所有录像
+func (m *Manager) ListRecordings() ([]models.RecordingInfo, error) {
+
+	return listRecordings()
+}
+
+// PlayRecording 在新的只读会话中回放录像，返回会话 ID 供前端附加
+func (m *Manager) PlayRecording(name string, speed float64) (string, error) {
+
+	path, err := resolveRecording(name)
+	if err != nil {
+		return "", err
+	}
+
+	session, err := m.websocketManager.startPlayback(path, speed)
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("开始回放录像 %s，会话 %s", name, session.ID)
+	return session.ID, nil
+}
+
+// SetPlaybackSpeed 调整回放会话的速度
+func (m *Manager) SetPlaybackSpeed(sessionID string, speed float64) error {
+
+	session, err := m.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.playback == nil {
+		return fmt.Errorf("不是回放会话: %s", sessionID)
+	}
+	return session.playback.setSpeed(speed)
+}
+
+// Shutdown 停止WebSocket服务器并结束所有会话
+//
+// 会话进程先收到 SIGHUP，超过 timeout 仍未退出时被强制终止；timeout 不大于 0 时使用 ShutdownTimeout。
+func (m *Manager) Shutdown(timeout time.Duration) (models.ShutdownReport, error) {
+
+	if timeout <= 0 {
+		timeout = ShutdownTimeout
+	}
+
+	m.cancel()
+
+	if m.websocketManager == nil {
+		return models.ShutdownReport{}, nil
+	}
+	// 先停止服务器，避免结束会话期间有新的连接创建会话
+	err := m.websocketManager.StopWebSocketServer(timeout)
+	report := m.websocketManager.closeAllSessions(timeout)
+	return report, err
+}
+
+// Close 以默认超时结束所有会话并停止WebSocket服务器
+func (m *Manager) Close() error {
+
+	_, err := m.Shutdown(ShutdownTimeout)
+	return err
+}
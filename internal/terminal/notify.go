@@ -0,0 +1,186 @@
+package terminal
+
+import (
+	"time"
+
+	"edex-ui-golang/internal/models"
+)
+
+// EventCommandCompleted 后台标签页中的长时间命令结束，数据为 models.CommandCompletion
+const EventCommandCompleted = "command-completed"
+
+// defaultLongCommandThreshold 未配置时，命令运行超过该时长才提醒
+const defaultLongCommandThreshold = 10 * time.Second
+
+// outputIdleGap 无法获取前台进程时，输出停止超过该时长视为命令结束
+const outputIdleGap = 3 * time.Second
+
+// 命令结束的判断来源
+const (
+	completionShellIntegration = "shell-integration" // OSC 133 标记，包含退出码
+	completionProcess          = "process"           // 前台作业回到 shell
+	completionIdle             = "idle"              // 持续的输出停止
+)
+
+// notifyConfig 长时间命令提醒的配置
+type notifyConfig struct {
+	enabled   bool
+	threshold time.Duration
+	sound     bool
+}
+
+// activityState 会话活动状态，用于在没有 shell 集成时判断命令何时结束，由 TerminalSession.mu 保护
+type activityState struct {
+	// 能否获取前台进程，不能时退而根据输出空闲判断
+	foregroundKnown bool
+	// 当前前台作业的开始时间与描述，shell 位于前台时为零值
+	busySince time.Time
+	busyTitle string
+	busyCmd   string
+	// 最近一段连续输出的开始与最后时间
+	burstStart time.Time
+	lastOutput time.Time
+	// 最近一次输入的时间，用于空闲超时
+	lastInput time.Time
+}
+
+// notifyConfigFromSettings 从设置中读取提醒配置
+func notifyConfigFromSettings(settings *models.Settings) notifyConfig {
+
+	threshold := time.Duration(settings.LongCommandThreshold) * time.Second
+	if threshold <= 0 {
+		threshold = defaultLongCommandThreshold
+	}
+	return notifyConfig{
+		enabled:   settings.NotifyLongCommands,
+		threshold: threshold,
+		sound:     settings.Audio && settings.LongCommandSound,
+	}
+}
+
+// SetCommandNotify 根据设置更新长时间命令提醒配置，立即生效
+func (m *Manager) SetCommandNotify(settings *models.Settings) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.notify = notifyConfigFromSettings(settings)
+}
+
+// SetActiveSession 记录前端当前显示的会话，该会话中结束的命令不提醒
+func (m *Manager) SetActiveSession(sessionID string) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.activeSession = sessionID
+}
+
+// commandCompleted 处理会话报告的命令结束，满足条件时通知前端
+func (m *Manager) commandCompleted(completion models.CommandCompletion) {
+
+	m.mu.RLock()
+	config, active := m.notify, m.activeSession
+	m.mu.RUnlock()
+
+	if !config.enabled || completion.SessionID == active {
+		return
+	}
+	if time.Duration(completion.Duration)*time.Millisecond < config.threshold {
+		return
+	}
+
+	if config.sound {
+		completion.Sound = "info"
+		if completion.ExitCode != nil && *completion.ExitCode != 0 {
+			completion.Sound = "error"
+		}
+	}
+	m.emit(EventCommandCompleted, completion)
+}
+
+// reportCompletion 报告命令结束
+func (ts *TerminalSession) reportCompletion(completion models.CommandCompletion) {
+
+	if ts.onCompletion == nil {
+		return
+	}
+
+	ts.mu.RLock()
+	completion.SessionID = ts.ID
+	completion.Title = ts.Title
+	ts.mu.RUnlock()
+
+	ts.onCompletion(completion)
+}
+
+// noteOutput 记录输出时间，用于输出空闲判断
+func (ts *TerminalSession) noteOutput(now time.Time) {
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	act := &ts.activity
+	if act.burstStart.IsZero() || now.Sub(act.lastOutput) > outputIdleGap {
+		act.burstStart = now
+	}
+	act.lastOutput = now
+}
+
+// noteForegroundLocked 根据前台进程变化判断作业是否结束，ts.mu 已锁定
+//
+// 返回结束的作业；启用了 shell 集成的会话由 OSC 133 标记报告，这里不重复报告。
+func (ts *TerminalSession) noteForegroundLocked(change models.TerminalProcessChange, now time.Time) (models.CommandCompletion, bool) {
+
+	act := &ts.activity
+	act.foregroundKnown = true
+
+	if change.Busy {
+		if act.busySince.IsZero() {
+			act.busySince = now
+			act.busyTitle = change.Title
+			act.busyCmd = change.Cmdline
+		}
+		return models.CommandCompletion{}, false
+	}
+
+	if act.busySince.IsZero() {
+		return models.CommandCompletion{}, false
+	}
+	completion := models.CommandCompletion{
+		Command:  act.busyCmd,
+		Process:  act.busyTitle,
+		Duration: now.Sub(act.busySince).Milliseconds(),
+		Source:   completionProcess,
+	}
+	act.busySince = time.Time{}
+	return completion, !ts.commands.enabled
+}
+
+// pollIdle 无法获取前台进程且没有 shell 集成时，以持续输出的停止作为命令结束
+func (ts *TerminalSession) pollIdle(now time.Time) {
+
+	ts.mu.Lock()
+	act := &ts.activity
+	if act.foregroundKnown || ts.commands.enabled || act.burstStart.IsZero() || now.Sub(act.lastOutput) < outputIdleGap {
+		ts.mu.Unlock()
+		return
+	}
+	completion := models.CommandCompletion{
+		Duration: act.lastOutput.Sub(act.burstStart).Milliseconds(),
+		Source:   completionIdle,
+	}
+	act.burstStart = time.Time{}
+	ts.mu.Unlock()
+
+	ts.reportCompletion(completion)
+}
+
+// commandFinished 由 shell 集成识别的命令结束时报告
+func (ts *TerminalSession) commandFinished(cmd models.TerminalCommand) {
+
+	ts.reportCompletion(models.CommandCompletion{
+		Command:  cmd.Command,
+		Duration: cmd.Duration,
+		ExitCode: cmd.ExitCode,
+		Source:   completionShellIntegration,
+	})
+}
@@ -0,0 +1,119 @@
+package terminal
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// oscMaxPayload OSC 序列内容的最大长度，超出的序列被丢弃
+const oscMaxPayload = 4096
+
+// oscScanner 从输出流中提取 OSC（ESC ] ... BEL / ESC \）序列，序列可以跨越多次读取
+type oscScanner struct {
+	state    int
+	payload  []byte
+	overflow bool
+	// 当前序列已扫描的字节数（含 ESC ]），用于计算序列起点
+	length int
+}
+
+// oscScanner 的状态
+const (
+	oscNormal     = iota
+	oscEscape     // 读到 ESC
+	oscBody       // 位于 OSC 内容中
+	oscBodyEscape // OSC 内容中读到 ESC，可能是 ST
+)
+
+// feed 扫描一段输出，对每个完整的 OSC 序列调用 fn
+//
+// start 与 end 为序列起点与终止符之后在 p 中的位置，调用方可据此计算序列在输出流中的偏移；
+// 序列开始于之前的数据时 start 为负数。
+func (s *oscScanner) feed(p []byte, fn func(payload []byte, start, end int)) {
+
+	for i, b := range p {
+		s.length++
+		switch s.state {
+		case oscNormal:
+			if b == 0x1b {
+				s.state = oscEscape
+				s.length = 1
+			}
+		case oscEscape:
+			if b == ']' {
+				s.state = oscBody
+				s.payload = s.payload[:0]
+				s.overflow = false
+			} else if b == 0x1b {
+				s.length = 1
+			} else {
+				s.state = oscNormal
+			}
+		case oscBody:
+			switch b {
+			case 0x07:
+				s.finish(i+1, fn)
+			case 0x1b:
+				s.state = oscBodyEscape
+			default:
+				s.append(b)
+			}
+		case oscBodyEscape:
+			if b == '\\' {
+				s.finish(i+1, fn)
+			} else {
+				// ESC 开始了新的转义序列，当前 OSC 未正常结束
+				s.state = oscNormal
+				if b == ']' {
+					s.state = oscBody
+					s.payload = s.payload[:0]
+					s.overflow = false
+					s.length = 2
+				}
+			}
+		}
+	}
+}
+
+// append 追加 OSC 内容
+func (s *oscScanner) append(b byte) {
+
+	if len(s.payload) >= oscMaxPayload {
+		s.overflow = true
+		return
+	}
+	s.payload = append(s.payload, b)
+}
+
+// finish 结束当前 OSC 序列
+func (s *oscScanner) finish(end int, fn func(payload []byte, start, end int)) {
+
+	s.state = oscNormal
+	if !s.overflow {
+		fn(s.payload, end-s.length, end)
+	}
+}
+
+// windowsDrivePath 形如 /C:/Users 的路径
+var windowsDrivePath = regexp.MustCompile(`^/[A-Za-z]:`)
+
+// parseOSC7 解析 OSC 7 工作目录通知，内容形如 7;file://host/path
+func parseOSC7(payload []byte) (string, bool) {
+
+	text := string(payload)
+	if !strings.HasPrefix(text, "7;") {
+		return "", false
+	}
+
+	u, err := url.Parse(text[2:])
+	if err != nil || u.Scheme != "file" || u.Path == "" {
+		return "", false
+	}
+
+	path := u.Path
+	if windowsDrivePath.MatchString(path) {
+		path = path[1:]
+	}
+	return path, true
+}
@@ -0,0 +1,129 @@
+package terminal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// oscHit 扫描到的 OSC 序列及其在整个输出流中的偏移
+type oscHit struct {
+	payload    string
+	start, end int
+}
+
+// scanOSC 将 stream 按 chunks 给出的长度切分后依次送入扫描器
+func scanOSC(stream string, chunks []int) []oscHit {
+
+	var s oscScanner
+	var hits []oscHit
+	base := 0
+	for _, n := range chunks {
+		chunk := stream[base : base+n]
+		chunkBase := base
+		s.feed([]byte(chunk), func(payload []byte, start, end int) {
+			hits = append(hits, oscHit{string(payload), chunkBase + start, chunkBase + end})
+		})
+		base += n
+	}
+	return hits
+}
+
+func TestOSCScannerChunkBoundaries(t *testing.T) {
+
+	long := "\x1b]0;" + strings.Repeat("x", oscMaxPayload+10) + "\x07"
+	tests := []struct {
+		name   string
+		stream string
+		want   []oscHit
+	}{
+		{"BEL terminated", "a\x1b]7;file://h/tmp\x07b", []oscHit{{"7;file://h/tmp", 1, 18}}},
+		{"ST terminated", "\x1b]133;A\x1b\\$ ", []oscHit{{"133;A", 0, 9}}},
+		{"two sequences", "\x1b]133;C\x07out\x1b]133;D;1\x07", []oscHit{{"133;C", 0, 8}, {"133;D;1", 11, 21}}},
+		{"doubled ESC", "\x1b\x1b]0;t\x07", []oscHit{{"0;t", 1, 7}}},
+		{"interrupted by CSI", "\x1b]0;abc\x1b[31m\x1b]0;t\x07", []oscHit{{"0;t", 12, 18}}},
+		{"restarted inside body", "\x1b]0;abc\x1b]0;x\x07", []oscHit{{"0;x", 7, 13}}},
+		{"CSI is not OSC", "\x1b[1;31mred\x1b[0m", nil},
+		{"unterminated", "\x1b]0;never ends", nil},
+		{"overflow dropped", long + "\x1b]0;ok\x07", []oscHit{{"0;ok", len(long), len(long) + 7}}},
+	}
+
+	for _, tt := range tests {
+		// 整段送入
+		if got := scanOSC(tt.stream, []int{len(tt.stream)}); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: whole = %v, want %v", tt.name, got, tt.want)
+		}
+		// 在每个位置切成两段
+		for k := 0; k <= len(tt.stream) && k < 64; k++ {
+			if got := scanOSC(tt.stream, []int{k, len(tt.stream) - k}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: split at %d = %v, want %v", tt.name, k, got, tt.want)
+			}
+		}
+		// 逐字节送入
+		if len(tt.stream) < 256 {
+			ones := make([]int, len(tt.stream))
+			for i := range ones {
+				ones[i] = 1
+			}
+			if got := scanOSC(tt.stream, ones); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: byte by byte = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestParseOSC7(t *testing.T) {
+
+	tests := []struct {
+		payload string
+		want    string
+		ok      bool
+	}{
+		{"7;file://host/home/user", "/home/user", true},
+		{"7;file:///tmp/a%20b", "/tmp/a b", true},
+		{"7;file://host/C:/Users/me", "C:/Users/me", true},
+		{"7;file://host", "", false},
+		{"7;http://host/path", "", false},
+		{"7;", "", false},
+		{"0;title", "", false},
+		{"77;file://host/x", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := parseOSC7([]byte(tt.payload))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseOSC7(%q) = %q, %v; want %q, %v", tt.payload, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseOSC133(t *testing.T) {
+
+	tests := []struct {
+		payload string
+		kind    byte
+		params  []string
+		ok      bool
+	}{
+		{"133;A", 'A', []string{}, true},
+		{"133;B", 'B', []string{}, true},
+		{"133;C", 'C', []string{}, true},
+		{"133;D;0", 'D', []string{"0"}, true},
+		{"133;D;130;aid=1", 'D', []string{"130", "aid=1"}, true},
+		{"133;E", 0, nil, false},
+		{"133;a", 0, nil, false},
+		{"133;AB", 0, nil, false},
+		{"133;", 0, nil, false},
+		{"133", 0, nil, false},
+		{"7;file:///", 0, nil, false},
+	}
+	for _, tt := range tests {
+		mark, ok := parseOSC133([]byte(tt.payload))
+		if ok != tt.ok {
+			t.Errorf("parseOSC133(%q) ok = %v, want %v", tt.payload, ok, tt.ok)
+			continue
+		}
+		if ok && (mark.kind != tt.kind || !reflect.DeepEqual(mark.params, tt.params)) {
+			t.Errorf("parseOSC133(%q) = %c %q, want %c %q", tt.payload, mark.kind, mark.params, tt.kind, tt.params)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package terminal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 回放速度范围
+const (
+	minPlaybackSpeed = 0.1
+	maxPlaybackSpeed = 16.0
+)
+
+// playbackIdleLimit 回放时两次输出之间的最长等待（按原始时间计算），跳过录制时的长时间空闲
+const playbackIdleLimit = 3 * time.Second
+
+// playbackState 录像回放状态
+type playbackState struct {
+	mu    sync.Mutex
+	speed float64
+	Path  string
+}
+
+// setSpeed 调整回放速度
+func (p *playbackState) setSpeed(speed float64) error {
+
+	if speed < minPlaybackSpeed || speed > maxPlaybackSpeed {
+		return fmt.Errorf("回放速度必须在 %.1f 到 %.1f 之间", minPlaybackSpeed, maxPlaybackSpeed)
+	}
+
+	p.mu.Lock()
+	p.speed = speed
+	p.mu.Unlock()
+	return nil
+}
+
+// getSpeed 返回当前回放速度
+func (p *playbackState) getSpeed() float64 {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.speed
+}
+
+// newPlaybackSession 创建回放录像的只读会话
+//
+// 回放会话没有 PTY，客户端的输入帧会被忽略。
+func newPlaybackSession(id, path string, speed float64, scrollbackSize int) (*TerminalSession, error) {
+
+	header, err := readCastHeader(path)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &playbackState{Path: path}
+	if err := state.setSpeed(speed); err != nil {
+		return nil, err
+	}
+
+	session := newTerminalSession(id, scrollbackSize)
+	session.Kind = SessionKindPlayback
+	session.Title = filepath.Base(path)
+	session.cols = uint16(header.Width)
+	session.rows = uint16(header.Height)
+	session.playback = state
+	return session, nil
+}
+
+// play 按录像中的时间轴将输出写入会话
+func (ts *TerminalSession) play(onExit func(*TerminalSession)) {
+
+	if err := ts.playEvents(); err != nil {
+		log.Printf("会话 %s 回放录像失败: %v", ts.ID, err)
+		ts.broadcast([]byte(fmt.Sprintf("\r\n[回放失败: %v]\r\n", err)))
+	} else {
+		ts.broadcast([]byte("\r\n[回放结束]\r\n"))
+	}
+
+	// 回放结束后会话保留，直到被显式关闭
+	<-ts.done
+	if onExit != nil {
+		onExit(ts)
+	}
+}
+
+// playbackResize 回放录像中的尺寸变化（形如 120x40），通知 v1 客户端调整终端尺寸
+func (ts *TerminalSession) playbackResize(size string) {
+
+	var cols, rows uint16
+	if _, err := fmt.Sscanf(size, "%dx%d", &cols, &rows); err != nil || cols == 0 || rows == 0 {
+		log.Printf("会话 %s 忽略无效的录像尺寸: %q", ts.ID, size)
+		return
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.cols, ts.rows = cols, rows
+	for client := range ts.clients {
+		client.sendControl(msgResize, resizeData{Cols: cols, Rows: rows})
+	}
+}
+
+// playEvents 读取并回放录像中的事件
+func (ts *TerminalSession) playEvents() error {
+
+	file, err := os.Open(ts.playback.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+
+	// 跳过文件头
+	if _, err := reader.ReadBytes('\n'); err != nil {
+		return err
+	}
+
+	last := 0.0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var event []interface{}
+			if jsonErr := json.Unmarshal(line, &event); jsonErr != nil || len(event) != 3 {
+				return fmt.Errorf("无效的录像事件: %s", line)
+			}
+
+			at, _ := event[0].(float64)
+			kind, _ := event[1].(string)
+			data, _ := event[2].(string)
+
+			delay := time.Duration((at - last) * float64(time.Second))
+			if delay > playbackIdleLimit {
+				delay = playbackIdleLimit
+			}
+			last = at
+
+			if delay > 0 {
+				timer := time.NewTimer(time.Duration(float64(delay) / ts.playback.getSpeed()))
+				select {
+				case <-timer.C:
+				case <-ts.done:
+					timer.Stop()
+					return nil
+				}
+			}
+
+			switch kind {
+			case "o":
+				ts.broadcast([]byte(data))
+			case "r":
+				ts.playbackResize(data)
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,53 @@
+//go:build linux
+
+package terminal
+
+import (
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// procCwdSupported 当前平台能否读取其他进程的工作目录
+const procCwdSupported = true
+
+// processCwd 通过 /proc/<pid>/cwd 读取进程的工作目录
+func processCwd(pid int) (string, error) {
+	return os.Readlink("/proc/" + strconv.Itoa(pid) + "/cwd")
+}
+
+// sessionProcessGroups 扫描 /proc 找出属于会话 sid 的所有进程组
+//
+// 僵尸进程已无法接收信号，不计入结果。
+func sessionProcessGroups(sid int) ([]int, error) {
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []int
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		data, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+		// 进程名可能包含空格与括号，从最后一个 ')' 之后解析：state ppid pgrp session
+		end := strings.LastIndexByte(string(data), ')')
+		if end < 0 {
+			continue
+		}
+		fields := strings.Fields(string(data[end+1:]))
+		if len(fields) < 4 || fields[3] != strconv.Itoa(sid) || fields[0] == "Z" {
+			continue
+		}
+		if pgid, err := strconv.Atoi(fields[2]); err == nil && !slices.Contains(groups, pgid) {
+			groups = append(groups, pgid)
+		}
+	}
+	return groups, nil
+}
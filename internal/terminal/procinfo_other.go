@@ -0,0 +1,18 @@
+//go:build !linux
+
+package terminal
+
+import "fmt"
+
+// procCwdSupported 当前平台能否读取其他进程的工作目录
+const procCwdSupported = false
+
+// processCwd 当前平台不支持读取其他进程的工作目录，只能依赖 OSC 7
+func processCwd(pid int) (string, error) {
+	return "", fmt.Errorf("当前平台不支持读取进程 %d 的工作目录", pid)
+}
+
+// sessionProcessGroups 当前平台不支持枚举会话中的进程组，只能使用 shell 与前台进程组
+func sessionProcessGroups(sid int) ([]int, error) {
+	return nil, fmt.Errorf("当前平台不支持枚举会话 %d 的进程组", sid)
+}
@@ -0,0 +1,194 @@
+package terminal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// /webterminal 协议
+//
+// 旧版客户端（未协商子协议）：文本帧与二进制帧都作为输入写入终端，
+// 文本帧 ESC[8;rows;cols t 被解释为调整尺寸。服务端只发送二进制输出帧。
+//
+// v1 客户端通过 WebSocket 子协议 edex-terminal.v1 协商：
+//   - 二进制帧双向承载终端数据（输入 / 输出）
+//   - 文本帧承载 JSON 控制消息信封 {"v":1,"type":"...","data":{...}}
+
+// ProtocolVersion 当前控制协议版本
+const ProtocolVersion = 1
+
+// protocolV1 v1 协议对应的 WebSocket 子协议名
+const protocolV1 = "edex-terminal.v1"
+
+// 控制消息类型
+const (
+	msgHello      = "hello"       // 服务端 -> 客户端：连接建立后的会话信息
+	msgResize     = "resize"      // 双向：客户端调整终端尺寸 / 服务端通知回放录像的尺寸变化
+	msgSignal     = "send-signal" // 客户端 -> 服务端：向会话发送信号
+	msgSetTitle   = "set-title"   // 双向：设置 / 通知会话标题
+	msgExitStatus = "exit-status" // 服务端 -> 客户端：进程退出状态
+	msgRestart    = "restart"     // 客户端 -> 服务端：重启已退出的 shell
+	msgHeartbeat  = "heartbeat"   // 双向：心跳，服务端原样回应
+	msgError      = "error"       // 服务端 -> 客户端：控制消息处理失败
+)
+
+// controlMessage 控制消息信封
+type controlMessage struct {
+	V    int             `json:"v"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// helloData hello 消息内容
+type helloData struct {
+	Version   int    `json:"version"`
+	SessionID string `json:"sessionId"`
+	Title     string `json:"title,omitempty"`
+	Cols      uint16 `json:"cols"`
+	Rows      uint16 `json:"rows"`
+	ReadOnly  bool   `json:"readOnly"`
+}
+
+// resizeData resize 消息内容
+type resizeData struct {
+	Cols uint16 `json:"cols"`
+	Rows uint16 `json:"rows"`
+}
+
+// signalData send-signal 消息内容
+type signalData struct {
+	Signal string `json:"signal"`
+	Target string `json:"target,omitempty"` // foreground（默认）或 session
+}
+
+// titleData set-title 消息内容
+type titleData struct {
+	Title string `json:"title"`
+}
+
+// errorData error 消息内容
+type errorData struct {
+	Request string `json:"request,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// error 消息的错误码，会话触及限制时客户端据此区分原因
+const (
+	errCodeSessionLimit = "session-limit" // 会话数量已达上限
+	errCodeIdleTimeout  = "idle-timeout"  // 会话空闲超时被结束
+	errCodeMemoryLimit  = "memory-limit"  // 进程因超出内存限制被终止
+	errCodeProcessLimit = "pids-limit"    // 进程数达到上限，无法创建新进程
+	errCodeSpawnFailed  = "spawn-failed"  // 无法启动会话
+)
+
+// encodeControl 编码控制消息
+func encodeControl(msgType string, data interface{}) ([]byte, error) {
+
+	msg := controlMessage{V: ProtocolVersion, Type: msgType}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		msg.Data = raw
+	}
+	return json.Marshal(msg)
+}
+
+// decodeControl 解码并校验控制消息信封
+func decodeControl(frame []byte) (*controlMessage, error) {
+
+	var msg controlMessage
+	if err := json.Unmarshal(frame, &msg); err != nil {
+		return nil, fmt.Errorf("无效的控制消息: %v", err)
+	}
+	if msg.V != ProtocolVersion {
+		return nil, fmt.Errorf("不支持的协议版本: %d", msg.V)
+	}
+	if msg.Type == "" {
+		return nil, fmt.Errorf("控制消息缺少 type")
+	}
+	return &msg, nil
+}
+
+// parseLegacyResize 解析旧版客户端的 resize 控制序列 ESC[8;rows;cols t
+func parseLegacyResize(data string) (rows, cols uint16, ok bool) {
+
+	if !strings.HasPrefix(data, "\x1b[8;") || !strings.HasSuffix(data, "t") {
+		return 0, 0, false
+	}
+
+	body := strings.TrimSuffix(strings.TrimPrefix(data, "\x1b[8;"), "t")
+	parts := strings.Split(body, ";")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+
+	r, errR := strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 16)
+	c, errC := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 16)
+	if errR != nil || errC != nil || r == 0 || c == 0 {
+		return 0, 0, false
+	}
+	return uint16(r), uint16(c), true
+}
+
+// handleControl 处理 v1 客户端发送的控制消息
+func (ts *TerminalSession) handleControl(client *wsClient, frame []byte) {
+
+	msg, err := decodeControl(frame)
+	if err != nil {
+		client.sendControl(msgError, errorData{Message: err.Error()})
+		return
+	}
+
+	switch msg.Type {
+	case msgResize:
+		var data resizeData
+		if err = json.Unmarshal(msg.Data, &data); err == nil {
+			if data.Cols == 0 || data.Rows == 0 {
+				err = fmt.Errorf("无效的终端尺寸: %dx%d", data.Cols, data.Rows)
+			} else {
+				err = ts.resize(data.Rows, data.Cols)
+			}
+		}
+	case msgSignal:
+		var data signalData
+		if err = json.Unmarshal(msg.Data, &data); err == nil {
+			_, err = ts.signal(data.Signal, data.Target)
+		}
+	case msgSetTitle:
+		var data titleData
+		if err = json.Unmarshal(msg.Data, &data); err == nil {
+			ts.setTitle(data.Title)
+		}
+	case msgRestart:
+		err = ts.restart()
+	case msgHeartbeat:
+		client.sendControlRaw(msgHeartbeat, msg.Data)
+	default:
+		err = fmt.Errorf("未知的控制消息类型: %s", msg.Type)
+	}
+
+	if err != nil {
+		client.sendControl(msgError, errorData{Request: msg.Type, Message: err.Error()})
+	}
+}
+
+// handleViewerControl 处理观察者发送的控制消息，只允许心跳
+func (ts *TerminalSession) handleViewerControl(client *wsClient, frame []byte) {
+
+	msg, err := decodeControl(frame)
+	if err != nil {
+		client.sendControl(msgError, errorData{Message: err.Error()})
+		return
+	}
+
+	if msg.Type == msgHeartbeat {
+		client.sendControlRaw(msgHeartbeat, msg.Data)
+		return
+	}
+	client.sendControl(msgError, errorData{Request: msg.Type, Message: "只读连接不能控制会话"})
+}
@@ -0,0 +1,94 @@
+package terminal
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseLegacyResize(t *testing.T) {
+
+	tests := []struct {
+		in         string
+		rows, cols uint16
+		ok         bool
+	}{
+		{"\x1b[8;24;80t", 24, 80, true},
+		{"\x1b[8; 50 ; 200 t", 50, 200, true},
+		{"\x1b[8;65535;1t", 65535, 1, true},
+		{"\x1b[8;65536;80t", 0, 0, false},
+		{"\x1b[8;0;80t", 0, 0, false},
+		{"\x1b[8;24;0t", 0, 0, false},
+		{"\x1b[8;24t", 0, 0, false},
+		{"\x1b[8;24;80;1t", 0, 0, false},
+		{"\x1b[8;-1;80t", 0, 0, false},
+		{"\x1b[8;24;80", 0, 0, false},
+		{"\x1b[9;24;80t", 0, 0, false},
+		{"ls -la\n", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		rows, cols, ok := parseLegacyResize(tt.in)
+		if rows != tt.rows || cols != tt.cols || ok != tt.ok {
+			t.Errorf("parseLegacyResize(%q) = %d, %d, %v; want %d, %d, %v",
+				tt.in, rows, cols, ok, tt.rows, tt.cols, tt.ok)
+		}
+	}
+}
+
+func TestDecodeControl(t *testing.T) {
+
+	tests := []struct {
+		frame    string
+		wantType string
+		wantErr  bool
+	}{
+		{`{"v":1,"type":"resize","data":{"cols":80,"rows":24}}`, msgResize, false},
+		{`{"v":1,"type":"restart"}`, msgRestart, false},
+		{`{"v":1,"type":"future-message","data":null}`, "future-message", false},
+		{`{"v":2,"type":"resize"}`, "", true},
+		{`{"type":"resize"}`, "", true},
+		{`{"v":1}`, "", true},
+		{`{"v":1,"type":""}`, "", true},
+		{`{"v":"1","type":"resize"}`, "", true},
+		{`not json`, "", true},
+		{``, "", true},
+	}
+	for _, tt := range tests {
+		msg, err := decodeControl([]byte(tt.frame))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("decodeControl(%s) error = %v, wantErr %v", tt.frame, err, tt.wantErr)
+			continue
+		}
+		if err == nil && msg.Type != tt.wantType {
+			t.Errorf("decodeControl(%s).Type = %q, want %q", tt.frame, msg.Type, tt.wantType)
+		}
+	}
+}
+
+func TestEncodeControlRoundTrip(t *testing.T) {
+
+	frame, err := encodeControl(msgHello, helloData{Version: ProtocolVersion, SessionID: "s1", Cols: 80, Rows: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := decodeControl(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hello helloData
+	if err := json.Unmarshal(msg.Data, &hello); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != msgHello || hello.SessionID != "s1" || hello.Cols != 80 || hello.Rows != 24 {
+		t.Errorf("round trip = %s %+v", msg.Type, hello)
+	}
+
+	// 没有内容的消息不带 data 字段
+	frame, err = encodeControl(msgRestart, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(frame) != `{"v":1,"type":"restart"}` {
+		t.Errorf("encodeControl(restart, nil) = %s", frame)
+	}
+}
@@ -0,0 +1,277 @@
+//go:build !windows
+
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+	"syscall"
+
+	"github.com/creack/pty"
+	"golang.org/x/sys/unix"
+)
+
+// localPTY 本地 PTY 及其子进程
+type localPTY struct {
+	file   *os.File
+	cmd    *exec.Cmd
+	status ExitStatus
+	// 子进程被回收后关闭
+	exited chan struct{}
+	// 资源限制，没有限制时为 nil
+	limits *processLimits
+	// 进程退出时读取的、尚未报告的限制事件
+	mu            sync.Mutex
+	pendingEvents []limitEvent
+}
+
+// startLocalPTY 在新的 PTY 中启动进程
+func startLocalPTY(spec *spawnSpec) (*localPTY, error) {
+
+	newCmd := func() *exec.Cmd {
+		cmd := exec.Command(spec.Path)
+		cmd.Args = spec.Args
+		cmd.Dir = spec.Dir
+		cmd.Env = spec.Env
+		return cmd
+	}
+
+	// 资源限制在启动前配置，shell 运行前即已生效
+	limits := newProcessLimits(spec.Limits)
+	cmd := newCmd()
+	limits.prepare(cmd)
+	file, err := pty.StartWithSize(cmd, &pty.Winsize{Rows: spec.Rows, Cols: spec.Cols})
+	if err != nil && limits.retryWithoutCgroupFD(err) {
+		cmd = newCmd()
+		limits.prepare(cmd)
+		file, err = pty.StartWithSize(cmd, &pty.Winsize{Rows: spec.Rows, Cols: spec.Cols})
+	}
+	if err != nil {
+		limits.release()
+		return nil, fmt.Errorf("启动 PTY 失败: %v", err)
+	}
+	if err := limits.started(cmd.Process.Pid); err != nil {
+		log.Printf("限制进程 %d 的资源失败: %v", cmd.Process.Pid, err)
+	}
+
+	p := &localPTY{
+		file:   file,
+		cmd:    cmd,
+		exited: make(chan struct{}),
+		limits: limits,
+	}
+	go p.reap()
+
+	return p, nil
+}
+
+// reap 等待子进程退出并记录状态
+func (p *localPTY) reap() {
+
+	err := p.cmd.Wait()
+	p.status = exitStatusFromState(p.cmd.ProcessState, err)
+
+	// 删除 cgroup 前读取最后的事件，例如导致退出的 OOM
+	events := p.limits.events()
+	p.limits.release()
+	p.mu.Lock()
+	p.pendingEvents = append(p.pendingEvents, events...)
+	p.mu.Unlock()
+
+	close(p.exited)
+}
+
+// limitStatus 返回资源限制的实现方式与未能完整应用的原因
+func (p *localPTY) limitStatus() (string, string) {
+	return p.limits.status()
+}
+
+// limitEvents 返回上次调用以来新发生的资源限制事件
+func (p *localPTY) limitEvents() []limitEvent {
+
+	events := p.limits.events()
+	p.mu.Lock()
+	pending := p.pendingEvents
+	p.pendingEvents = nil
+	p.mu.Unlock()
+	return append(pending, events...)
+}
+
+// exitStatusFromState 将进程状态转换为 ExitStatus
+func exitStatusFromState(state *os.ProcessState, err error) ExitStatus {
+
+	if state == nil {
+		return ExitStatus{Code: -1}
+	}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return ExitStatus{Code: -1, Signal: unix.SignalName(ws.Signal())}
+	}
+	return ExitStatus{Code: state.ExitCode()}
+}
+
+func (p *localPTY) Read(b []byte) (int, error) {
+	return p.file.Read(b)
+}
+
+func (p *localPTY) Write(b []byte) (int, error) {
+	return p.file.Write(b)
+}
+
+// Resize 调整 PTY 尺寸
+func (p *localPTY) Resize(rows, cols uint16) error {
+	return pty.Setsize(p.file, &pty.Winsize{Rows: rows, Cols: cols})
+}
+
+// Close 终止会话中的所有进程并关闭 PTY 主端
+func (p *localPTY) Close() error {
+
+	// 先终止进程再关闭主端，关闭后无法再查询前台进程组
+	_, _ = p.signalGroups(unix.SIGKILL, p.sessionGroups())
+	p.signalSurvivors(unix.SIGKILL)
+	return p.file.Close()
+}
+
+// Hangup 向会话中的所有进程组发送 SIGHUP
+func (p *localPTY) Hangup() error {
+
+	select {
+	case <-p.exited:
+		// shell 已退出，只剩留在会话中的后台作业
+		p.signalSurvivors(unix.SIGHUP)
+		return nil
+	default:
+	}
+	_, err := p.signalGroups(unix.SIGHUP, p.sessionGroups())
+	return err
+}
+
+// Lingering 报告会话中是否仍有进程存活，包括 shell 退出后留下的后台作业
+func (p *localPTY) Lingering() bool {
+
+	select {
+	case <-p.exited:
+	default:
+		return true
+	}
+	groups, _ := sessionProcessGroups(p.cmd.Process.Pid)
+	return len(groups) > 0
+}
+
+// signalSurvivors 向会话中剩余的进程组发送信号，返回收到信号的进程组数
+//
+// 忽略 SIGHUP 或经 nohup 启动的后台作业在 shell 被回收后仍属于原会话，按会话 ID 查找；
+// 会话中仍有进程时会话 ID 不会被复用，因此不会误伤其他进程。
+func (p *localPTY) signalSurvivors(sig unix.Signal) int {
+
+	groups, _ := sessionProcessGroups(p.cmd.Process.Pid)
+	delivered := 0
+	for _, pgid := range groups {
+		if unix.Kill(-pgid, sig) == nil {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// Signal 向前台进程组或会话中的所有进程组发送信号
+func (p *localPTY) Signal(name, target string) (string, []int, error) {
+
+	sig := unix.SignalNum(name)
+	if sig == 0 {
+		return "", nil, fmt.Errorf("不支持的信号: %s", name)
+	}
+
+	groups := p.sessionGroups()
+	if target == SignalTargetForeground {
+		pgid, err := p.ForegroundPid()
+		if err != nil {
+			return "", nil, fmt.Errorf("无法获取前台进程组: %v", err)
+		}
+		groups = []int{pgid}
+	}
+	pgids, err := p.signalGroups(sig, groups)
+	return signalMethodKill, pgids, err
+}
+
+// sessionGroups 返回会话中的进程组
+//
+// shell 是会话首进程，其进程组 ID 与会话 ID 都等于 PID；前台作业通常在另一个进程组中，
+// 能枚举进程的平台上还包括后台作业所在的进程组。
+func (p *localPTY) sessionGroups() []int {
+
+	pid := p.cmd.Process.Pid
+	groups := []int{pid}
+	if pgid, err := p.ForegroundPid(); err == nil && pgid > 0 && pgid != pid {
+		groups = append(groups, pgid)
+	}
+	others, _ := sessionProcessGroups(pid)
+	for _, pgid := range others {
+		if !slices.Contains(groups, pgid) {
+			groups = append(groups, pgid)
+		}
+	}
+	return groups
+}
+
+// signalGroups 向进程组发送信号，返回实际收到信号的进程组
+//
+// 子进程已被回收时不再发送，避免 PID 被复用后误伤其他进程。
+func (p *localPTY) signalGroups(sig unix.Signal, groups []int) ([]int, error) {
+
+	select {
+	case <-p.exited:
+		return nil, nil
+	default:
+	}
+
+	var delivered []int
+	var firstErr error
+	for _, pgid := range groups {
+		err := unix.Kill(-pgid, sig)
+		if err == nil {
+			delivered = append(delivered, pgid)
+		} else if !errors.Is(err, unix.ESRCH) && firstErr == nil {
+			firstErr = fmt.Errorf("向进程组 %d 发送 %s 失败: %v", pgid, unix.SignalName(sig), err)
+		}
+	}
+	if len(delivered) == 0 && firstErr == nil {
+		firstErr = fmt.Errorf("进程组 %v 已不存在", groups)
+	}
+	return delivered, firstErr
+}
+
+// Wait 等待子进程退出
+func (p *localPTY) Wait() ExitStatus {
+
+	<-p.exited
+	return p.status
+}
+
+// Process 返回子进程
+func (p *localPTY) Process() *exec.Cmd {
+	return p.cmd
+}
+
+// ForegroundPid 返回 PTY 前台进程组 ID，即前台作业组长的 PID
+func (p *localPTY) ForegroundPid() (int, error) {
+
+	// 通过 SyscallConn 访问描述符，避免 File.Fd() 将其切换为阻塞模式
+	conn, err := p.file.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var pgid int
+	var ioctlErr error
+	if err := conn.Control(func(fd uintptr) {
+		pgid, ioctlErr = unix.IoctlGetInt(int(fd), unix.TIOCGPGRP)
+	}); err != nil {
+		return 0, err
+	}
+	return pgid, ioctlErr
+}
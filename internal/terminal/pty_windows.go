@@ -0,0 +1,75 @@
+//go:build windows
+
+package terminal
+
+import (
+	"os/exec"
+	"sync"
+
+	"github.com/iyzyi/aiopty/pty"
+)
+
+// localPTY 基于 ConPTY / WinPTY 的本地终端
+//
+// aiopty 不暴露子进程，因此无法获取 PID 与退出码，读取结束即视为进程退出。
+type localPTY struct {
+	p      *pty.Pty
+	once   sync.Once
+	exited chan struct{}
+}
+
+// startLocalPTY 在新的 PTY 中启动进程
+func startLocalPTY(spec *spawnSpec) (*localPTY, error) {
+
+	p, err := pty.OpenWithOptions(&pty.Options{
+		Path: spec.Path,
+		Args: spec.Args,
+		Dir:  spec.Dir,
+		Env:  spec.Env,
+		Size: &pty.WinSize{Cols: spec.Cols, Rows: spec.Rows},
+		Type: pty.AUTO,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &localPTY{p: p, exited: make(chan struct{})}, nil
+}
+
+func (l *localPTY) Read(b []byte) (int, error) {
+
+	n, err := l.p.Read(b)
+	if err != nil {
+		l.once.Do(func() { close(l.exited) })
+	}
+	return n, err
+}
+
+func (l *localPTY) Write(b []byte) (int, error) {
+	return l.p.Write(b)
+}
+
+// Resize 调整 PTY 尺寸
+func (l *localPTY) Resize(rows, cols uint16) error {
+	return l.p.SetSize(&pty.WinSize{Rows: rows, Cols: cols})
+}
+
+// Close 关闭 PTY
+func (l *localPTY) Close() error {
+
+	err := l.p.Close()
+	l.once.Do(func() { close(l.exited) })
+	return err
+}
+
+// Wait 等待输出结束，退出码无法获取
+func (l *localPTY) Wait() ExitStatus {
+
+	<-l.exited
+	return ExitStatus{Code: -1}
+}
+
+// Process 无法获取子进程
+func (l *localPTY) Process() *exec.Cmd {
+	return nil
+}
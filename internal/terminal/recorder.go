@@ -0,0 +1,281 @@
+package terminal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"edex-ui-golang/internal/models"
+	"edex-ui-golang/internal/utils"
+)
+
+// recordingsDirName 录像文件所在的子目录
+const recordingsDirName = "recordings"
+
+// recordingExt 录像文件扩展名
+const recordingExt = ".cast"
+
+// recorderFlushInterval 录像缓冲写入磁盘的最长间隔，程序崩溃时最多丢失这段时间内的输出
+const recorderFlushInterval = time.Second
+
+// castHeader asciicast v2 文件头
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Recorder 以 asciicast v2 格式记录会话的输出与尺寸变化
+type Recorder struct {
+	mu    sync.Mutex
+	file  *os.File
+	w     *bufio.Writer
+	start time.Time
+	// Close 时关闭，结束定时写盘
+	stop chan struct{}
+	// 上次输出末尾不完整的 UTF-8 序列，拼接到下一次输出之前
+	pending []byte
+	Path    string
+}
+
+// recordingsDir 返回录像目录，不存在时创建
+func recordingsDir() (string, error) {
+
+	appDir, err := utils.GetAppDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(appDir, recordingsDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// newRecorder 创建录像文件并写入文件头
+func newRecorder(path string, cols, rows uint16, title string, env map[string]string) (*Recorder, error) {
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("创建录像文件失败: %v", err)
+	}
+
+	now := time.Now()
+	rec := &Recorder{
+		file:  file,
+		w:     bufio.NewWriter(file),
+		start: now,
+		stop:  make(chan struct{}),
+		Path:  path,
+	}
+
+	header, err := json.Marshal(castHeader{
+		Version:   2,
+		Width:     int(cols),
+		Height:    int(rows),
+		Timestamp: now.Unix(),
+		Title:     title,
+		Env:       env,
+	})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	rec.w.Write(header)
+	rec.w.WriteByte('\n')
+
+	go rec.flushLoop()
+	return rec, nil
+}
+
+// flushLoop 定期将缓冲的事件写入磁盘，输出停止后最后一段也不会滞留在缓冲区中
+func (r *Recorder) flushLoop() {
+
+	ticker := time.NewTicker(recorderFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			if r.file != nil && r.w.Buffered() > 0 {
+				r.w.Flush()
+			}
+			r.mu.Unlock()
+		}
+	}
+}
+
+// output 记录一段输出
+func (r *Recorder) output(p []byte) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return
+	}
+
+	data := append(r.pending, p...)
+	cut := incompleteUTF8Tail(data)
+	r.pending = append([]byte(nil), data[cut:]...)
+	if cut == 0 {
+		return
+	}
+	r.writeEventLocked("o", string(data[:cut]))
+}
+
+// resize 记录终端尺寸变化
+func (r *Recorder) resize(cols, rows uint16) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return
+	}
+	r.writeEventLocked("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// writeEventLocked 写入一条事件，调用方需持有锁
+func (r *Recorder) writeEventLocked(kind, data string) {
+
+	event, err := json.Marshal([]interface{}{time.Since(r.start).Seconds(), kind, data})
+	if err != nil {
+		return
+	}
+	r.w.Write(event)
+	r.w.WriteByte('\n')
+}
+
+// Close 写入剩余数据并关闭录像文件
+func (r *Recorder) Close() error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	close(r.stop)
+
+	if len(r.pending) > 0 {
+		r.writeEventLocked("o", string(r.pending))
+		r.pending = nil
+	}
+
+	err := r.w.Flush()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	r.file = nil
+	return err
+}
+
+// incompleteUTF8Tail 返回 p 末尾不完整 UTF-8 序列的起始位置，不存在时返回 len(p)
+func incompleteUTF8Tail(p []byte) int {
+
+	// UTF-8 字符最长 4 字节，只需检查末尾 3 个字节
+	for i := len(p) - 1; i >= 0 && i >= len(p)-3; i-- {
+		if !utf8.RuneStart(p[i]) {
+			continue
+		}
+		if !utf8.FullRune(p[i:]) {
+			return i
+		}
+		break
+	}
+	return len(p)
+}
+
+// listRecordings 列出录像目录中的所有录像
+func listRecordings() ([]models.RecordingInfo, error) {
+
+	dir, err := recordingsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	recordings := []models.RecordingInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), recordingExt) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		rec := models.RecordingInfo{
+			Name:    entry.Name(),
+			Path:    filepath.Join(dir, entry.Name()),
+			Size:    info.Size(),
+			ModTime: info.ModTime().Unix(),
+		}
+		if header, err := readCastHeader(rec.Path); err == nil {
+			rec.Width = header.Width
+			rec.Height = header.Height
+			rec.Title = header.Title
+			rec.Timestamp = header.Timestamp
+		}
+		recordings = append(recordings, rec)
+	}
+
+	return recordings, nil
+}
+
+// readCastHeader 读取录像文件头
+func readCastHeader(path string) (*castHeader, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return nil, err
+	}
+
+	var header castHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, fmt.Errorf("无效的录像文件头: %v", err)
+	}
+	if header.Version != 2 {
+		return nil, fmt.Errorf("不支持的 asciicast 版本: %d", header.Version)
+	}
+	return &header, nil
+}
+
+// resolveRecording 将录像名解析为录像目录中的路径，拒绝目录穿越
+func resolveRecording(name string) (string, error) {
+
+	if name == "" || name != filepath.Base(name) || !strings.HasSuffix(name, recordingExt) {
+		return "", fmt.Errorf("无效的录像名: %q", name)
+	}
+
+	dir, err := recordingsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
@@ -0,0 +1,67 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorderFlushesWhenIdle(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "idle"+recordingExt)
+	rec, err := newRecorder(path, 80, 24, "idle", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Close()
+
+	rec.output([]byte("last burst"))
+
+	// 之后没有新的事件，缓冲仍应在 recorderFlushInterval 内写入磁盘
+	deadline := time.Now().Add(3 * recorderFlushInterval)
+	for {
+		data, _ := os.ReadFile(path)
+		if strings.Contains(string(data), "last burst") {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("output not flushed after %v: %q", 3*recorderFlushInterval, data)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestPlaybackAppliesResizeEvents(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "resize"+recordingExt)
+	cast := `{"version":2,"width":80,"height":24}
+[0.0,"o","before"]
+[0.001,"r","120x40"]
+[0.002,"o","after"]
+[0.003,"r","bogus"]
+`
+	if err := os.WriteFile(path, []byte(cast), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := newPlaybackSession("play-test", path, 1, minScrollbackSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.cols != 80 || session.rows != 24 {
+		t.Fatalf("initial size = %dx%d, want 80x24", session.cols, session.rows)
+	}
+	if err := session.playEvents(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 无效的尺寸事件被忽略，保留最近一次有效尺寸
+	if session.cols != 120 || session.rows != 40 {
+		t.Errorf("size after playback = %dx%d, want 120x40", session.cols, session.rows)
+	}
+	if got := string(session.scrollback.Bytes()); got != "beforeafter" {
+		t.Errorf("output = %q, want %q", got, "beforeafter")
+	}
+}
@@ -0,0 +1,165 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// SessionRegistry 会话注册表
+//
+// 所有会话（本地 shell、录像回放等）都登记在这里，
+// 并通过同一个 WebSocket 服务的 /webterminal?session=<id> 访问。
+type SessionRegistry struct {
+	mu       sync.RWMutex
+	sessions map[string]*TerminalSession
+	// 可同时存在的会话上限（不含录像回放），0 为不限制
+	limit int
+}
+
+// errSessionLimit 会话数量已达上限
+var errSessionLimit = errors.New("终端会话数量已达上限")
+
+// newSessionRegistry 创建会话注册表
+func newSessionRegistry() *SessionRegistry {
+
+	return &SessionRegistry{
+		sessions: make(map[string]*TerminalSession),
+	}
+}
+
+// Get 按 ID 获取会话
+func (r *SessionRegistry) Get(sessionID string) (*TerminalSession, bool) {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	session, exists := r.sessions[sessionID]
+	return session, exists
+}
+
+// List 按创建时间返回所有会话
+func (r *SessionRegistry) List() []*TerminalSession {
+
+	r.mu.RLock()
+	sessions := make([]*TerminalSession, 0, len(r.sessions))
+	for _, session := range r.sessions {
+		sessions = append(sessions, session)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].CreatedAt.Equal(sessions[j].CreatedAt) {
+			return sessions[i].ID < sessions[j].ID
+		}
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions
+}
+
+// IDs 返回所有会话 ID
+func (r *SessionRegistry) IDs() []string {
+
+	sessions := r.List()
+	ids := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		ids = append(ids, session.ID)
+	}
+	return ids
+}
+
+// Len 返回会话数量
+func (r *SessionRegistry) Len() int {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.sessions)
+}
+
+// setLimit 设置会话数量上限，已存在的会话不受影响
+func (r *SessionRegistry) setLimit(limit int) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limit = limit
+}
+
+// checkLimitLocked 检查能否再登记一个会话，r.mu 已锁定
+func (r *SessionRegistry) checkLimitLocked() error {
+
+	if r.limit <= 0 {
+		return nil
+	}
+	count := 0
+	for _, session := range r.sessions {
+		if session.Kind != SessionKindPlayback {
+			count++
+		}
+	}
+	if count >= r.limit {
+		return fmt.Errorf("%w（%d 个），请先关闭不用的会话", errSessionLimit, r.limit)
+	}
+	return nil
+}
+
+// checkLimit 检查能否再登记一个会话，用于在启动后端前提前拒绝
+func (r *SessionRegistry) checkLimit() error {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.checkLimitLocked()
+}
+
+// add 登记新会话，ID 已存在或会话数量已达上限时返回错误
+func (r *SessionRegistry) add(session *TerminalSession) error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.sessions[session.ID]; exists {
+		return fmt.Errorf("会话已存在: %s", session.ID)
+	}
+	if session.Kind != SessionKindPlayback {
+		if err := r.checkLimitLocked(); err != nil {
+			return err
+		}
+	}
+	r.sessions[session.ID] = session
+	return nil
+}
+
+// getOrCreate 查找会话，不存在时调用 create 创建并登记
+//
+// create 在持有注册表锁的情况下执行，保证同一 ID 不会被并发创建两次。
+func (r *SessionRegistry) getOrCreate(sessionID string, create func(string) (*TerminalSession, error)) (*TerminalSession, bool, error) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session, exists := r.sessions[sessionID]; exists {
+		return session, false, nil
+	}
+	if err := r.checkLimitLocked(); err != nil {
+		return nil, false, err
+	}
+
+	session, err := create(sessionID)
+	if err != nil {
+		return nil, false, err
+	}
+	r.sessions[sessionID] = session
+	return session, true, nil
+}
+
+// remove 从注册表移除会话，仅当登记的仍是同一个会话对象时生效
+func (r *SessionRegistry) remove(session *TerminalSession) bool {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if current, exists := r.sessions[session.ID]; exists && current == session {
+		delete(r.sessions, session.ID)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package terminal
+
+import (
+	"bytes"
+	"sync"
+)
+
+// DefaultScrollbackSize 默认每个会话保留的输出字节数
+const DefaultScrollbackSize = 1024 * 1024
+
+// minScrollbackSize 允许配置的最小缓冲区大小
+const minScrollbackSize = 4 * 1024
+
+// maxScrollbackSize 允许配置的最大缓冲区大小，保持在客户端发送队列上限以下
+const maxScrollbackSize = sendQueueBytes / 2
+
+// Scrollback 有界的会话输出历史（环形缓冲区）
+//
+// 偏移量是自会话开始以来的绝对字节位置：缓冲区满后最旧的数据被覆盖，
+// 但已经返回给调用方的偏移量依然有效，只是可能落在保留窗口之外。
+type Scrollback struct {
+	mu    sync.RWMutex
+	buf   []byte
+	start int   // 最旧字节在 buf 中的位置
+	size  int   // 当前保留的字节数
+	total int64 // 累计写入的字节数，即保留窗口的结束偏移
+}
+
+// NewScrollback 创建指定容量的输出历史
+func NewScrollback(capacity int) *Scrollback {
+
+	capacity = min(max(capacity, minScrollbackSize), maxScrollbackSize)
+	return &Scrollback{buf: make([]byte, capacity)}
+}
+
+// Write 追加输出，超出容量时覆盖最旧的数据
+func (s *Scrollback) Write(p []byte) (int, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(p)
+	s.total += int64(n)
+
+	capacity := len(s.buf)
+	if n >= capacity {
+		// 只需保留末尾 capacity 字节
+		copy(s.buf, p[n-capacity:])
+		s.start = 0
+		s.size = capacity
+		return n, nil
+	}
+
+	end := (s.start + s.size) % capacity
+	copied := copy(s.buf[end:], p)
+	copy(s.buf, p[copied:])
+
+	s.size += n
+	if s.size > capacity {
+		s.start = (s.start + s.size - capacity) % capacity
+		s.size = capacity
+	}
+	return n, nil
+}
+
+// Window 返回当前保留窗口的起止偏移 [first, end)
+func (s *Scrollback) Window() (first, end int64) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.total - int64(s.size), s.total
+}
+
+// Bytes 返回全部保留的输出副本
+func (s *Scrollback) Bytes() []byte {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.copyLocked(0, s.size)
+}
+
+// Range 返回偏移区间 [from, to) 的输出，区间会被裁剪到保留窗口内
+//
+// to 小于等于 0 表示直到末尾。返回值中的 start 为实际数据的起始偏移。
+func (s *Scrollback) Range(from, to int64) (data []byte, start int64) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	first := s.total - int64(s.size)
+	if to <= 0 || to > s.total {
+		to = s.total
+	}
+	if from < first {
+		from = first
+	}
+	if from >= to {
+		return nil, from
+	}
+
+	return s.copyLocked(int(from-first), int(to-from)), from
+}
+
+// LastLines 返回最后 n 行输出及其起始偏移
+//
+// 末尾未以换行结束的部分算作一行。n 小于等于 0 时返回空。
+func (s *Scrollback) LastLines(n int) (data []byte, start int64) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	first := s.total - int64(s.size)
+	if n <= 0 || s.size == 0 {
+		return nil, s.total
+	}
+
+	all := s.copyLocked(0, s.size)
+
+	// 从末尾向前数换行；末尾的换行属于最后一行本身
+	end := len(all)
+	if all[end-1] == '\n' {
+		end--
+	}
+	cut := 0
+	for i := 0; i < n; i++ {
+		idx := bytes.LastIndexByte(all[:end], '\n')
+		if idx < 0 {
+			cut = 0
+			break
+		}
+		cut = idx + 1
+		end = idx
+	}
+
+	return all[cut:], first + int64(cut)
+}
+
+// copyLocked 复制保留窗口内相对位置 [offset, offset+length) 的数据，调用方需持有锁
+func (s *Scrollback) copyLocked(offset, length int) []byte {
+
+	out := make([]byte, length)
+	if length == 0 {
+		return out
+	}
+
+	capacity := len(s.buf)
+	pos := (s.start + offset) % capacity
+	copied := copy(out, s.buf[pos:min(pos+length, capacity)])
+	copy(out[copied:], s.buf[:length-copied])
+	return out
+}
@@ -0,0 +1,116 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScrollbackRange(t *testing.T) {
+
+	// 容量为 minScrollbackSize，写入的数据会环绕
+	s := NewScrollback(minScrollbackSize)
+	line := strings.Repeat("x", 99) + "\n"
+	for i := 0; i < 50; i++ {
+		_, _ = s.Write([]byte(line))
+	}
+	_, _ = s.Write([]byte("tail"))
+
+	first, end := s.Window()
+	if end != 50*100+4 {
+		t.Fatalf("end = %d, want %d", end, 50*100+4)
+	}
+	if end-first != minScrollbackSize {
+		t.Fatalf("window size = %d, want %d", end-first, minScrollbackSize)
+	}
+
+	tests := []struct {
+		name      string
+		from, to  int64
+		wantStart int64
+		wantLen   int
+	}{
+		{"whole window", 0, 0, first, minScrollbackSize},
+		{"before window is clipped", first - 500, first + 10, first, 10},
+		{"inside window", first + 100, first + 200, first + 100, 100},
+		{"to beyond end", end - 4, end + 100, end - 4, 4},
+		{"empty range", first + 10, first + 10, first + 10, 0},
+		{"reversed range", first + 20, first + 10, first + 20, 0},
+	}
+	for _, tt := range tests {
+		data, start := s.Range(tt.from, tt.to)
+		if start != tt.wantStart || len(data) != tt.wantLen {
+			t.Errorf("%s: Range(%d, %d) = start %d len %d, want start %d len %d",
+				tt.name, tt.from, tt.to, start, len(data), tt.wantStart, tt.wantLen)
+		}
+	}
+
+	// 环绕后取出的内容与写入的内容一致
+	data, _ := s.Range(end-104, end)
+	if got, want := string(data), line+"tail"; got != want {
+		t.Errorf("Range across wrap = %q, want %q", got, want)
+	}
+}
+
+func TestScrollbackLargeWrite(t *testing.T) {
+
+	s := NewScrollback(minScrollbackSize)
+	_, _ = s.Write([]byte("head"))
+	big := strings.Repeat("a", minScrollbackSize-1) + "z"
+	_, _ = s.Write([]byte(big + "!"))
+
+	first, end := s.Window()
+	if end != int64(4+len(big)+1) || end-first != minScrollbackSize {
+		t.Fatalf("Window() = %d, %d", first, end)
+	}
+	data := s.Bytes()
+	if !strings.HasSuffix(string(data), "z!") || strings.Contains(string(data), "head") {
+		t.Errorf("Bytes() kept wrong data: ...%q", data[len(data)-8:])
+	}
+}
+
+func TestScrollbackLastLines(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		writes []string
+		n      int
+		want   string
+	}{
+		{"empty buffer", nil, 3, ""},
+		{"zero lines", []string{"a\nb\n"}, 0, ""},
+		{"trailing newline belongs to last line", []string{"a\nb\nc\n"}, 2, "b\nc\n"},
+		{"unterminated last line", []string{"a\nb\nc"}, 2, "b\nc"},
+		{"more lines than available", []string{"a\nb"}, 10, "a\nb"},
+		{"split across writes", []string{"a\nb", "c\nd"}, 2, "bc\nd"},
+	}
+	for _, tt := range tests {
+		s := NewScrollback(minScrollbackSize)
+		for _, w := range tt.writes {
+			_, _ = s.Write([]byte(w))
+		}
+		data, start := s.LastLines(tt.n)
+		if string(data) != tt.want {
+			t.Errorf("%s: LastLines(%d) = %q, want %q", tt.name, tt.n, data, tt.want)
+		}
+		if _, end := s.Window(); start != end-int64(len(data)) {
+			t.Errorf("%s: start = %d, want %d", tt.name, start, end-int64(len(data)))
+		}
+	}
+}
+
+func TestScrollbackLastLinesAfterWrap(t *testing.T) {
+
+	s := NewScrollback(minScrollbackSize)
+	for i := 0; i < 100; i++ {
+		_, _ = s.Write([]byte(strings.Repeat("y", 63) + "\n"))
+	}
+	_, _ = s.Write([]byte("last\n"))
+
+	data, start := s.LastLines(2)
+	if got, want := string(data), strings.Repeat("y", 63)+"\nlast\n"; got != want {
+		t.Errorf("LastLines(2) = %q, want %q", got, want)
+	}
+	if _, end := s.Window(); start != end-int64(len(data)) {
+		t.Errorf("start = %d, want %d", start, end-int64(len(data)))
+	}
+}
@@ -0,0 +1,131 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"edex-ui-golang/internal/models"
+)
+
+// 输出历史搜索的限制
+const (
+	defaultSearchResults = 200
+	maxSearchResults     = 5000
+	maxSearchContext     = 20
+)
+
+// compileSearch 根据搜索参数生成正则表达式
+func compileSearch(opts models.ScrollbackSearch) (*regexp.Regexp, error) {
+
+	if opts.Query == "" {
+		return nil, fmt.Errorf("搜索内容不能为空")
+	}
+
+	pattern := regexp.QuoteMeta(opts.Query)
+	if opts.Regex {
+		// 先单独编译，错误信息中不出现下面追加的标志
+		if _, err := regexp.Compile(opts.Query); err != nil {
+			return nil, fmt.Errorf("无效的正则表达式 %q: %v", opts.Query, err)
+		}
+		pattern = opts.Query
+	}
+	if !opts.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// searchOutput 在去除转义序列后的输出中查找匹配，base 为 data 的起始偏移
+//
+// 空匹配（例如 a*）没有可跳转的内容，会被忽略。返回的第二个值表示匹配数超过了 limit。
+func searchOutput(data []byte, base int64, re *regexp.Regexp, limit, context int) ([]models.ScrollbackMatch, bool) {
+
+	text, offsets := stripANSIMapped(data, true)
+
+	matches := []models.ScrollbackMatch{}
+	for _, loc := range re.FindAllIndex(text, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		if len(matches) == limit {
+			return matches, true
+		}
+
+		lineStart := bytes.LastIndexByte(text[:loc[0]], '\n') + 1
+		lineEnd := len(text)
+		if idx := bytes.IndexByte(text[loc[1]-1:], '\n'); idx >= 0 {
+			lineEnd = loc[1] - 1 + idx
+		}
+
+		match := models.ScrollbackMatch{
+			Start:  base + int64(offsets[loc[0]]),
+			End:    base + int64(offsets[loc[1]-1]) + 1,
+			Text:   string(text[loc[0]:loc[1]]),
+			Line:   string(text[lineStart:lineEnd]),
+			Column: utf8.RuneCount(text[lineStart:loc[0]]),
+		}
+		match.Before, match.After = contextLines(text, lineStart, lineEnd, context)
+		matches = append(matches, match)
+	}
+	return matches, false
+}
+
+// contextLines 返回 text[lineStart:lineEnd] 之前与之后各最多 n 行
+func contextLines(text []byte, lineStart, lineEnd, n int) (before, after []string) {
+
+	end := lineStart - 1
+	for i := 0; i < n && end >= 0; i++ {
+		start := bytes.LastIndexByte(text[:end], '\n') + 1
+		before = append([]string{string(text[start:end])}, before...)
+		end = start - 1
+	}
+
+	start := lineEnd + 1
+	for i := 0; i < n && start <= len(text); i++ {
+		end := len(text)
+		if idx := bytes.IndexByte(text[start:], '\n'); idx >= 0 {
+			end = start + idx
+		} else if start == len(text) {
+			// 末尾换行之后没有内容
+			break
+		}
+		after = append(after, string(text[start:end]))
+		start = end + 1
+	}
+	return before, after
+}
+
+// SearchScrollback 在会话输出历史中搜索，返回匹配的偏移与所在行
+//
+// 偏移可直接用于 GetOutputRange，因此即使前端已丢弃对应的行也能定位匹配。
+func (m *Manager) SearchScrollback(sessionID string, opts models.ScrollbackSearch) (*models.ScrollbackSearchResult, error) {
+
+	session, err := m.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	re, err := compileSearch(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := opts.MaxResults
+	if limit <= 0 {
+		limit = defaultSearchResults
+	}
+	limit = min(limit, maxSearchResults)
+	context := min(max(opts.Context, 0), maxSearchContext)
+
+	data, start := session.scrollback.Range(opts.From, opts.To)
+	first, end := session.scrollback.Window()
+	matches, truncated := searchOutput(data, start, re, limit, context)
+	return &models.ScrollbackSearchResult{
+		SessionID: sessionID,
+		First:     first,
+		End:       end,
+		Matches:   matches,
+		Truncated: truncated,
+	}, nil
+}
@@ -0,0 +1,121 @@
+package terminal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"edex-ui-golang/internal/models"
+)
+
+func TestCompileSearch(t *testing.T) {
+
+	if _, err := compileSearch(models.ScrollbackSearch{}); err == nil {
+		t.Error("empty query: want error")
+	}
+
+	_, err := compileSearch(models.ScrollbackSearch{Query: "a(", Regex: true})
+	if err == nil || strings.Contains(err.Error(), "(?i)") {
+		t.Errorf("invalid regex error = %v, want error without internal flags", err)
+	}
+
+	tests := []struct {
+		opts  models.ScrollbackSearch
+		text  string
+		match bool
+	}{
+		{models.ScrollbackSearch{Query: "a.c"}, "abc", false},
+		{models.ScrollbackSearch{Query: "a.c"}, "A.C", true},
+		{models.ScrollbackSearch{Query: "a.c", CaseSensitive: true}, "A.C", false},
+		{models.ScrollbackSearch{Query: "a.c", Regex: true}, "ABC", true},
+		{models.ScrollbackSearch{Query: "a.c", Regex: true, CaseSensitive: true}, "ABC", false},
+	}
+	for _, tt := range tests {
+		re, err := compileSearch(tt.opts)
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.opts, err)
+		}
+		if got := re.MatchString(tt.text); got != tt.match {
+			t.Errorf("%+v on %q = %v, want %v", tt.opts, tt.text, got, tt.match)
+		}
+	}
+}
+
+func TestSearchOutputRawOffsets(t *testing.T) {
+
+	// 去除转义序列后为 "foobar\nbaq\n"，偏移需对应原始输出
+	raw := []byte("foo\x1b[1mbar\x1b[0m\r\nbaz\bq\n")
+	const base = 100
+
+	tests := []struct {
+		query      string
+		start, end int64
+		line       string
+		column     int
+		before     []string
+		after      []string
+	}{
+		{"bar", base + 7, base + 10, "foobar", 3, nil, []string{"baq"}},
+		{"baq", base + 16, base + 21, "baq", 0, []string{"foobar"}, nil},
+		{"r\nb", base + 9, base + 17, "foobar\nbaq", 5, nil, nil},
+	}
+	for _, tt := range tests {
+		re, err := compileSearch(models.ScrollbackSearch{Query: tt.query})
+		if err != nil {
+			t.Fatal(err)
+		}
+		matches, truncated := searchOutput(raw, base, re, 10, 1)
+		if truncated || len(matches) != 1 {
+			t.Fatalf("%q: %d matches (truncated %v), want 1", tt.query, len(matches), truncated)
+		}
+		m := matches[0]
+		if m.Start != tt.start || m.End != tt.end {
+			t.Errorf("%q: offsets = [%d, %d), want [%d, %d)", tt.query, m.Start, m.End, tt.start, tt.end)
+		}
+		if m.Text != tt.query || m.Line != tt.line || m.Column != tt.column {
+			t.Errorf("%q: text %q line %q column %d, want line %q column %d", tt.query, m.Text, m.Line, m.Column, tt.line, tt.column)
+		}
+		if !reflect.DeepEqual(m.Before, tt.before) {
+			t.Errorf("%q: before = %q, want %q", tt.query, m.Before, tt.before)
+		}
+		if !reflect.DeepEqual(m.After, tt.after) {
+			t.Errorf("%q: after = %q, want %q", tt.query, m.After, tt.after)
+		}
+	}
+}
+
+func TestSearchOutputLimitAndEmptyMatches(t *testing.T) {
+
+	re, _ := compileSearch(models.ScrollbackSearch{Query: "a", CaseSensitive: true})
+	matches, truncated := searchOutput([]byte("a a a"), 0, re, 2, 0)
+	if len(matches) != 2 || !truncated {
+		t.Errorf("got %d matches (truncated %v), want 2 truncated", len(matches), truncated)
+	}
+
+	re, _ = compileSearch(models.ScrollbackSearch{Query: "x*", Regex: true})
+	if matches, _ := searchOutput([]byte("abc"), 0, re, 10, 0); len(matches) != 0 {
+		t.Errorf("empty matches reported: %+v", matches)
+	}
+}
+
+func TestContextLines(t *testing.T) {
+
+	text := []byte("l1\nl2\nmatch\nl4\nl5\n")
+	lineStart := strings.Index(string(text), "match")
+	lineEnd := lineStart + len("match")
+
+	tests := []struct {
+		n             int
+		before, after []string
+	}{
+		{0, nil, nil},
+		{1, []string{"l2"}, []string{"l4"}},
+		{5, []string{"l1", "l2"}, []string{"l4", "l5"}},
+	}
+	for _, tt := range tests {
+		before, after := contextLines(text, lineStart, lineEnd, tt.n)
+		if !reflect.DeepEqual(before, tt.before) || !reflect.DeepEqual(after, tt.after) {
+			t.Errorf("n=%d: got %q / %q, want %q / %q", tt.n, before, after, tt.before, tt.after)
+		}
+	}
+}
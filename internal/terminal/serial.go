@@ -0,0 +1,144 @@
+package terminal
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"edex-ui-golang/internal/models"
+)
+
+// 串口默认参数
+const (
+	defaultSerialBaud     = 115200
+	defaultSerialDataBits = 8
+	defaultSerialStopBits = 1
+)
+
+// 校验位与流控取值
+const (
+	serialParityNone  = "none"
+	serialParityOdd   = "odd"
+	serialParityEven  = "even"
+	serialParityMark  = "mark"
+	serialParitySpace = "space"
+
+	serialFlowNone    = "none"
+	serialFlowRTSCTS  = "rtscts"
+	serialFlowXONXOFF = "xonxoff"
+)
+
+// serialClosedBanner 串口设备断开后显示在终端中的提示
+const serialClosedBanner = "\r\n\x1b[0m[串口已断开。按 Enter 重新打开设备]\r\n"
+
+// normalizeSerialOptions 补全默认值并校验串口参数
+func normalizeSerialOptions(opts models.SerialOptions) (models.SerialOptions, error) {
+
+	opts.Device = strings.TrimSpace(opts.Device)
+	if opts.Device == "" {
+		return opts, fmt.Errorf("串口设备不能为空")
+	}
+
+	if opts.Baud == 0 {
+		opts.Baud = defaultSerialBaud
+	}
+	if opts.Baud < 0 {
+		return opts, fmt.Errorf("无效的波特率: %d", opts.Baud)
+	}
+
+	if opts.DataBits == 0 {
+		opts.DataBits = defaultSerialDataBits
+	}
+	if opts.DataBits < 5 || opts.DataBits > 8 {
+		return opts, fmt.Errorf("无效的数据位: %d", opts.DataBits)
+	}
+
+	if opts.StopBits == 0 {
+		opts.StopBits = defaultSerialStopBits
+	}
+	if opts.StopBits != 1 && opts.StopBits != 2 {
+		return opts, fmt.Errorf("无效的停止位: %d", opts.StopBits)
+	}
+
+	opts.Parity = strings.ToLower(opts.Parity)
+	switch opts.Parity {
+	case "":
+		opts.Parity = serialParityNone
+	case serialParityNone, serialParityOdd, serialParityEven, serialParityMark, serialParitySpace:
+	default:
+		return opts, fmt.Errorf("无效的校验位: %s", opts.Parity)
+	}
+
+	opts.FlowControl = strings.ToLower(opts.FlowControl)
+	switch opts.FlowControl {
+	case "":
+		opts.FlowControl = serialFlowNone
+	case serialFlowNone, serialFlowRTSCTS, serialFlowXONXOFF:
+	default:
+		return opts, fmt.Errorf("无效的流控方式: %s", opts.FlowControl)
+	}
+
+	return opts, nil
+}
+
+// serialDesc 返回形如 /dev/ttyUSB0 115200 8N1 的描述
+func serialDesc(opts models.SerialOptions) string {
+
+	desc := fmt.Sprintf("%s %d %d%s%d", opts.Device, opts.Baud, opts.DataBits,
+		strings.ToUpper(opts.Parity[:1]), opts.StopBits)
+	if opts.FlowControl != serialFlowNone {
+		desc += " " + opts.FlowControl
+	}
+	return desc
+}
+
+// CreateSerialSession 打开串口设备并创建会话
+//
+// 设备断开后按 Enter 会以相同参数重新打开。
+func (m *Manager) CreateSerialSession(opts models.SerialOptions) (*models.TerminalSessionInfo, error) {
+
+	if err := m.websocketManager.sessions.checkLimit(); err != nil {
+		return nil, err
+	}
+
+	opts, err := normalizeSerialOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	title := opts.Title
+	if title == "" {
+		title = opts.Device
+	}
+
+	session := m.newSession(newSessionID(), title)
+	session.Kind = SessionKindSerial
+	session.shellDesc = serialDesc(opts)
+	session.launch = func(cols, rows uint16) (sessionBackend, error) {
+		port, err := openSerial(opts)
+		if err != nil {
+			return nil, err
+		}
+		return port, nil
+	}
+
+	err = session.spawn()
+	if err != nil {
+		return nil, err
+	}
+	m.startSession(session)
+
+	if err := m.websocketManager.sessions.add(session); err != nil {
+		session.close()
+		return nil, err
+	}
+
+	log.Printf("已创建串口会话 %s (%s)", session.ID, session.shellDesc)
+	info := session.info()
+	return &info, nil
+}
+
+// ListSerialDevices 列出本机可用的串口设备
+func (m *Manager) ListSerialDevices() ([]models.SerialDevice, error) {
+	return listSerialDevices()
+}
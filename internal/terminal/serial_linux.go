@@ -0,0 +1,238 @@
+//go:build linux
+
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"edex-ui-golang/internal/models"
+	"golang.org/x/sys/unix"
+)
+
+// sysClassTTY 内核导出的 tty 设备目录
+const sysClassTTY = "/sys/class/tty"
+
+// serialPort 打开的串口设备
+//
+// 设备以非阻塞方式打开并交给 Go 的网络轮询器，关闭文件即可中断阻塞中的 Read。
+type serialPort struct {
+	file *os.File
+
+	mu     sync.Mutex
+	closed bool
+	// 设备读取结束（断开或关闭）后关闭
+	exited   chan struct{}
+	exitOnce sync.Once
+}
+
+// openSerial 按参数打开并配置串口设备
+func openSerial(opts models.SerialOptions) (*serialPort, error) {
+
+	fd, err := unix.Open(opts.Device, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		if errors.Is(err, unix.EACCES) {
+			return nil, fmt.Errorf("没有权限打开串口 %s，请将当前用户加入 dialout 组", opts.Device)
+		}
+		return nil, fmt.Errorf("打开串口 %s 失败: %v", opts.Device, err)
+	}
+
+	if err := configureSerial(fd, opts); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("配置串口 %s 失败: %v", opts.Device, err)
+	}
+	// 独占设备，避免其他程序同时读取导致数据被分走
+	_ = unix.IoctlSetInt(fd, unix.TIOCEXCL, 0)
+	// 丢弃打开前残留在缓冲区中的数据
+	_ = unix.IoctlSetInt(fd, unix.TCFLSH, unix.TCIOFLUSH)
+
+	return &serialPort{
+		file:   os.NewFile(uintptr(fd), opts.Device),
+		exited: make(chan struct{}),
+	}, nil
+}
+
+// configureSerial 将设备设置为原始模式并应用波特率、数据位、校验位、停止位与流控
+//
+// 使用 termios2（BOTHER）设置波特率，因此支持非标准波特率。
+func configureSerial(fd int, opts models.SerialOptions) error {
+
+	tio, err := unix.IoctlGetTermios(fd, unix.TCGETS2)
+	if err != nil {
+		return err
+	}
+
+	// 原始模式，等价于 cfmakeraw
+	tio.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP |
+		unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON | unix.IXOFF | unix.IXANY
+	tio.Oflag &^= unix.OPOST
+	tio.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	tio.Cflag &^= unix.CSIZE | unix.PARENB | unix.PARODD | unix.CMSPAR | unix.CSTOPB | unix.CRTSCTS | unix.CBAUD
+	tio.Cflag |= unix.CREAD | unix.CLOCAL
+	tio.Cc[unix.VMIN] = 1
+	tio.Cc[unix.VTIME] = 0
+
+	switch opts.DataBits {
+	case 5:
+		tio.Cflag |= unix.CS5
+	case 6:
+		tio.Cflag |= unix.CS6
+	case 7:
+		tio.Cflag |= unix.CS7
+	default:
+		tio.Cflag |= unix.CS8
+	}
+
+	switch opts.Parity {
+	case serialParityOdd:
+		tio.Cflag |= unix.PARENB | unix.PARODD
+	case serialParityEven:
+		tio.Cflag |= unix.PARENB
+	case serialParityMark:
+		tio.Cflag |= unix.PARENB | unix.CMSPAR | unix.PARODD
+	case serialParitySpace:
+		tio.Cflag |= unix.PARENB | unix.CMSPAR
+	}
+	if opts.Parity != serialParityNone {
+		tio.Iflag |= unix.INPCK
+	} else {
+		tio.Iflag &^= unix.INPCK
+	}
+
+	if opts.StopBits == 2 {
+		tio.Cflag |= unix.CSTOPB
+	}
+
+	switch opts.FlowControl {
+	case serialFlowRTSCTS:
+		tio.Cflag |= unix.CRTSCTS
+	case serialFlowXONXOFF:
+		tio.Iflag |= unix.IXON | unix.IXOFF
+	}
+
+	tio.Cflag |= unix.BOTHER
+	tio.Ispeed = uint32(opts.Baud)
+	tio.Ospeed = uint32(opts.Baud)
+
+	return unix.IoctlSetTermios(fd, unix.TCSETS2, tio)
+}
+
+// Read 读取设备输出，设备断开后返回错误
+func (p *serialPort) Read(b []byte) (int, error) {
+
+	n, err := p.file.Read(b)
+	if err != nil {
+		p.exitOnce.Do(func() { close(p.exited) })
+	}
+	return n, err
+}
+
+func (p *serialPort) Write(b []byte) (int, error) {
+	return p.file.Write(b)
+}
+
+// Resize 串口没有窗口尺寸的概念
+func (p *serialPort) Resize(rows, cols uint16) error {
+	return nil
+}
+
+// Close 关闭设备
+func (p *serialPort) Close() error {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	return p.file.Close()
+}
+
+// Wait 等待设备读取结束，串口没有退出码
+func (p *serialPort) Wait() ExitStatus {
+
+	<-p.exited
+	return ExitStatus{Code: -1}
+}
+
+// Process 串口没有本地子进程
+func (p *serialPort) Process() *exec.Cmd {
+	return nil
+}
+
+// listSerialDevices 扫描 /sys/class/tty，列出背后有实际设备的 tty
+//
+// 虚拟终端（tty0、pts 等）没有 device 链接，被排除；
+// 内核为 8250 驱动预留但未探测到硬件的 ttyS* 其 type 为 0，同样排除。
+func listSerialDevices() ([]models.SerialDevice, error) {
+
+	entries, err := os.ReadDir(sysClassTTY)
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %v", sysClassTTY, err)
+	}
+
+	devices := make([]models.SerialDevice, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		dir := filepath.Join(sysClassTTY, name)
+
+		devicePath, err := filepath.EvalSymlinks(filepath.Join(dir, "device"))
+		if err != nil {
+			continue
+		}
+		if portType, err := readSysAttr(dir, "type"); err == nil && portType == "0" {
+			continue
+		}
+
+		path := filepath.Join("/dev", name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		device := models.SerialDevice{
+			Path:        path,
+			Name:        name,
+			Description: usbDescription(devicePath),
+		}
+		if driver, err := os.Readlink(filepath.Join(devicePath, "driver")); err == nil {
+			device.Driver = filepath.Base(driver)
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// usbDescription 沿设备目录向上查找 USB 设备的厂商与产品名
+//
+// ttyUSB 的 device 指向 USB 接口下的端口，ttyACM 指向 USB 接口，厂商信息在 USB 设备目录中。
+func usbDescription(devicePath string) string {
+
+	dir := devicePath
+	for i := 0; i < 3; i++ {
+		product, err := readSysAttr(dir, "product")
+		if err == nil {
+			if manufacturer, err := readSysAttr(dir, "manufacturer"); err == nil && manufacturer != "" {
+				return manufacturer + " " + product
+			}
+			return product
+		}
+		dir = filepath.Dir(dir)
+	}
+	return ""
+}
+
+// readSysAttr 读取 sysfs 属性文件
+func readSysAttr(dir, name string) (string, error) {
+
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package terminal
+
+import (
+	"fmt"
+
+	"edex-ui-golang/internal/models"
+)
+
+// openSerial 当前平台暂不支持串口会话
+func openSerial(opts models.SerialOptions) (sessionBackend, error) {
+	return nil, fmt.Errorf("当前平台暂不支持串口会话")
+}
+
+// listSerialDevices 当前平台暂不支持列出串口设备
+func listSerialDevices() ([]models.SerialDevice, error) {
+	return nil, fmt.Errorf("当前平台暂不支持列出串口设备")
+}
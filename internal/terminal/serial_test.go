@@ -0,0 +1,47 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+
+	"edex-ui-golang/internal/models"
+)
+
+func TestNormalizeSerialOptions(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		opts     models.SerialOptions
+		wantDesc string
+		wantErr  string
+	}{
+		{"defaults", models.SerialOptions{Device: " /dev/ttyUSB0 "}, "/dev/ttyUSB0 115200 8N1", ""},
+		{"explicit values", models.SerialOptions{Device: "COM3", Baud: 9600, DataBits: 7, Parity: "even", StopBits: 2}, "COM3 9600 7E2", ""},
+		{"parity case folded", models.SerialOptions{Device: "/dev/ttyS0", Parity: "Odd"}, "/dev/ttyS0 115200 8O1", ""},
+		{"mark and space parity", models.SerialOptions{Device: "/dev/ttyS0", DataBits: 5, Parity: "space"}, "/dev/ttyS0 115200 5S1", ""},
+		{"flow control shown", models.SerialOptions{Device: "/dev/ttyACM0", FlowControl: "RTSCTS"}, "/dev/ttyACM0 115200 8N1 rtscts", ""},
+		{"missing device", models.SerialOptions{Device: "  "}, "", "设备不能为空"},
+		{"negative baud", models.SerialOptions{Device: "/dev/ttyS0", Baud: -1}, "", "波特率"},
+		{"too few data bits", models.SerialOptions{Device: "/dev/ttyS0", DataBits: 4}, "", "数据位"},
+		{"too many data bits", models.SerialOptions{Device: "/dev/ttyS0", DataBits: 9}, "", "数据位"},
+		{"invalid stop bits", models.SerialOptions{Device: "/dev/ttyS0", StopBits: 3}, "", "停止位"},
+		{"invalid parity", models.SerialOptions{Device: "/dev/ttyS0", Parity: "parity"}, "", "校验位"},
+		{"invalid flow control", models.SerialOptions{Device: "/dev/ttyS0", FlowControl: "dtrdsr"}, "", "流控"},
+	}
+	for _, tt := range tests {
+		opts, err := normalizeSerialOptions(tt.opts)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := serialDesc(opts); got != tt.wantDesc {
+			t.Errorf("%s: desc = %q, want %q", tt.name, got, tt.wantDesc)
+		}
+	}
+}
@@ -0,0 +1,613 @@
+package terminal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"edex-ui-golang/internal/models"
+	"github.com/gorilla/websocket"
+)
+
+// 会话类型
+const (
+	SessionKindLocal     = "local"     // 本地 PTY 中的 shell
+	SessionKindPlayback  = "playback"  // 录像回放，只读
+	SessionKindSSH       = "ssh"       // 内置 SSH 客户端连接的远程 shell
+	SessionKindSerial    = "serial"    // 串口设备
+	SessionKindContainer = "container" // Docker/Podman 容器中的 exec 会话
+)
+
+// sessionIDPattern 合法的会话 ID：字母、数字、下划线、连字符与点，最长 64 个字符
+var sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// TerminalSession 终端会话
+//
+// 会话的生命周期独立于 WebSocket 连接：连接断开后 PTY 继续在后台运行，
+// 新的连接可以通过相同的会话 ID 重新附加并收到最近输出的回放。
+type TerminalSession struct {
+	ID        string
+	Kind      string
+	CreatedAt time.Time
+	Process   *exec.Cmd
+	mu        sync.RWMutex
+	closed    bool
+	// 会话后端，统一通过 PTY 交互；回放会话为 nil
+	backend     sessionBackend
+	ProcessName string
+	// 本地 PTY 的启动参数，重启 shell 时复用
+	spec *spawnSpec
+	// 按给定尺寸启动后端，重启时复用；回放会话为 nil
+	launch func(cols, rows uint16) (sessionBackend, error)
+	// 正在重启后端，避免同时按下的多次回车重复启动
+	restarting bool
+	// 串行化对后端的尺寸调整，调整本身不持有 mu
+	resizeMu sync.Mutex
+	// 会话信息中显示的 shell 描述，例如 /bin/bash、user@host:22
+	shellDesc string
+	// 本地会话使用的 shell 配置 ID
+	profileID string
+	// 进程退出后记录的状态，运行中为 nil
+	exitStatus *ExitStatus
+	// 当前附加到会话的客户端
+	clients map[*wsClient]struct{}
+	// 会话标题，由客户端设置
+	Title string
+	// 输出历史，用于重新附加时回放以及后端检索
+	scrollback *Scrollback
+	// 会话结束时关闭
+	done chan struct{}
+	// 当前终端尺寸
+	cols, rows uint16
+	// 正在进行的录像，未录制时为 nil
+	recorder *Recorder
+	// 回放会话的状态，普通会话为 nil
+	playback *playbackState
+	// 前台进程的工作目录
+	Cwd string
+	// 最近一次检测到的前台进程
+	foreground models.TerminalProcessChange
+	// 上次从 /proc 读取的工作目录，仅由 track 协程访问
+	lastProcCwd string
+	// 输出中的 OSC 序列扫描状态，仅由 pump 协程访问
+	osc oscScanner
+	// 向前端发送事件，未设置时为 nil
+	emit EventEmitter
+	// shell 集成识别的命令记录
+	commands commandLog
+	// 用于判断长时间命令结束的活动状态
+	activity activityState
+	// 命令结束时调用，未设置时为 nil
+	onCompletion func(models.CommandCompletion)
+	// 输出触发规则的执行器与规则来源，未设置时不检查
+	triggers     *triggerRunner
+	triggerRules func() []compiledTrigger
+	// 空闲超时，0 为不限制；超时后调用 onIdle 结束会话
+	idleTimeout time.Duration
+	onIdle      func(*TerminalSession)
+}
+
+// signalChars 可通过终端控制字符触发的信号
+var signalChars = map[string]byte{
+	"SIGINT":  0x03,
+	"SIGQUIT": 0x1c,
+	"SIGTSTP": 0x1a,
+}
+
+// newSessionID 生成随机会话 ID
+func newSessionID() string {
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		// 随机源不可用时极少发生，退化为不可预测性较低的 ID
+		return fmt.Sprintf("s%p", &buf)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// validateSessionID 校验客户端提供的会话 ID
+func validateSessionID(id string) error {
+
+	if !sessionIDPattern.MatchString(id) {
+		return fmt.Errorf("无效的会话 ID: %q", id)
+	}
+	return nil
+}
+
+// newTerminalSession 创建会话对象（尚未启动进程）
+func newTerminalSession(id string, scrollbackSize int) *TerminalSession {
+
+	return &TerminalSession{
+		ID:         id,
+		Kind:       SessionKindLocal,
+		CreatedAt:  time.Now(),
+		clients:    make(map[*wsClient]struct{}),
+		scrollback: NewScrollback(scrollbackSize),
+		done:       make(chan struct{}),
+	}
+}
+
+// attach 附加客户端并回放最近输出
+func (ts *TerminalSession) attach(client *wsClient) {
+
+	// 持有写锁期间完成回放，保证回放与后续实时输出之间不丢失、不重复
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	client.sendControl(msgHello, helloData{
+		Version:   ProtocolVersion,
+		SessionID: ts.ID,
+		Title:     ts.Title,
+		Cols:      ts.cols,
+		Rows:      ts.rows,
+		ReadOnly:  ts.playback != nil || client.readOnly,
+	})
+
+	if replay := ts.scrollback.Bytes(); len(replay) > 0 {
+		client.enqueue(outFrame{data: replay}, true)
+	}
+	ts.clients[client] = struct{}{}
+}
+
+// detach 分离客户端，会话继续在后台运行
+func (ts *TerminalSession) detach(client *wsClient) {
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	delete(ts.clients, client)
+}
+
+// info 返回会话信息
+func (ts *TerminalSession) info() models.TerminalSessionInfo {
+
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	info := models.TerminalSessionInfo{
+		ID:        ts.ID,
+		Title:     ts.Title,
+		Kind:      ts.Kind,
+		CreatedAt: ts.CreatedAt.Unix(),
+		Clients:   len(ts.clients),
+		Running:   ts.backend != nil && ts.exitStatus == nil && !ts.closed,
+		Recording: ts.recorder != nil,
+	}
+	info.Profile = ts.profileID
+	info.Shell = ts.shellDesc
+	info.Cwd = ts.Cwd
+	info.Process = ts.foreground.Title
+	info.Busy = ts.foreground.Busy && info.Running
+	info.ShellIntegration = ts.commands.enabled
+	if reporter, ok := ts.backend.(limitReporter); ok {
+		info.Limits, info.LimitsNote = reporter.limitStatus()
+	}
+	if ts.exitStatus != nil {
+		code := ts.exitStatus.Code
+		info.ExitCode = &code
+	}
+	return info
+}
+
+// clientCount 返回当前附加的客户端数量
+func (ts *TerminalSession) clientCount() int {
+
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return len(ts.clients)
+}
+
+// pump 持续读取 PTY 输出，保存到输出历史并分发给所有附加的客户端
+//
+// 读取循环与连接无关，因此没有客户端时输出也不会阻塞或丢失。
+// 进程退出后会话保留，等待重启或显式关闭。
+func (ts *TerminalSession) pump(backend sessionBackend) {
+
+	buffer := make([]byte, 32*1024)
+	for {
+		n, err := backend.Read(buffer)
+		if n > 0 {
+			// 先写入输出历史再扫描，命令标记处理时可以读取已到达的命令输入
+			_, base := ts.scrollback.Window()
+			ts.noteOutput(time.Now())
+			ts.broadcast(buffer[:n])
+			ts.scanOutput(buffer[:n], base)
+			ts.feedTriggers(buffer[:n], base)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	status := backend.Wait()
+
+	ts.mu.Lock()
+	if ts.closed || ts.backend != backend {
+		ts.mu.Unlock()
+		return
+	}
+	ts.exitStatus = &status
+	ts.mu.Unlock()
+
+	log.Printf("会话 %s 的进程已退出: code=%d signal=%s", ts.ID, status.Code, status.Signal)
+
+	ts.finishRunningCommand()
+	ts.reportLimitEvents(backend, true)
+
+	if ts.Kind == SessionKindSerial {
+		ts.broadcast([]byte(serialClosedBanner))
+	} else {
+		ts.broadcast([]byte(exitBanner(status)))
+	}
+	ts.notifyExit(status)
+}
+
+// exitBanner 进程退出后显示在终端中的提示
+func exitBanner(status ExitStatus) string {
+
+	reason := fmt.Sprintf("退出码 %d", status.Code)
+	if status.Signal != "" {
+		reason = "被信号 " + status.Signal + " 终止"
+	} else if status.Code < 0 {
+		reason = "退出码未知"
+	}
+	return fmt.Sprintf("\r\n\x1b[0m[进程已退出，%s。按 Enter 重启 shell]\r\n", reason)
+}
+
+// restart 以相同的 shell、参数、工作目录与环境重启已退出的进程
+func (ts *TerminalSession) restart() error {
+
+	ts.mu.Lock()
+	switch {
+	case ts.closed:
+		ts.mu.Unlock()
+		return fmt.Errorf("会话 %s 已关闭", ts.ID)
+	case ts.launch == nil:
+		ts.mu.Unlock()
+		return fmt.Errorf("会话 %s 不支持重启", ts.ID)
+	case ts.exitStatus == nil:
+		ts.mu.Unlock()
+		return fmt.Errorf("会话 %s 的进程仍在运行", ts.ID)
+	case ts.restarting:
+		ts.mu.Unlock()
+		return fmt.Errorf("会话 %s 正在重启", ts.ID)
+	}
+	ts.restarting = true
+	old := ts.backend
+	ts.mu.Unlock()
+
+	defer func() {
+		ts.mu.Lock()
+		ts.restarting = false
+		ts.mu.Unlock()
+	}()
+
+	if old != nil {
+		_ = old.Close()
+	}
+	if err := ts.spawn(); err != nil {
+		return err
+	}
+
+	log.Printf("会话 %s 已重启 shell", ts.ID)
+	return nil
+}
+
+// broadcast 记录输出并发送给所有客户端
+func (ts *TerminalSession) broadcast(p []byte) {
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	_, _ = ts.scrollback.Write(p)
+	if ts.recorder != nil {
+		ts.recorder.output(p)
+	}
+
+	if len(ts.clients) == 0 {
+		return
+	}
+
+	// p 可能是 pump 的读缓冲区，复制一份供各客户端的发送队列共享
+	data := make([]byte, len(p))
+	copy(data, p)
+	for client := range ts.clients {
+		client.writeBinary(data)
+	}
+}
+
+// write 写入 PTY 输入
+//
+// 进程已退出时，回车会重启 shell，其余输入被丢弃。
+func (ts *TerminalSession) write(p []byte) {
+
+	ts.mu.RLock()
+	backend, exited := ts.backend, ts.exitStatus != nil
+	ts.mu.RUnlock()
+
+	if exited {
+		if strings.ContainsAny(string(p), "\r\n") {
+			if err := ts.restart(); err != nil {
+				log.Printf("重启会话 %s 失败: %v", ts.ID, err)
+			}
+		}
+		return
+	}
+	if backend != nil {
+		ts.noteInput(time.Now())
+		_, _ = backend.Write(p)
+	}
+}
+
+// running 会话进程是否仍在运行
+func (ts *TerminalSession) running() bool {
+
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return ts.backend != nil && ts.exitStatus == nil && !ts.closed
+}
+
+// resize 调整 PTY 尺寸
+//
+// 容器等后端的尺寸调整是同步的网络请求，调用期间不持有 mu，
+// 以免阻塞输出分发；resizeMu 保证多个客户端的调整按顺序生效。
+func (ts *TerminalSession) resize(rows, cols uint16) error {
+
+	ts.resizeMu.Lock()
+	defer ts.resizeMu.Unlock()
+
+	ts.mu.RLock()
+	backend := ts.backend
+	ts.mu.RUnlock()
+
+	if backend == nil {
+		return fmt.Errorf("会话 %s 尚未启动", ts.ID)
+	}
+	if err := backend.Resize(rows, cols); err != nil {
+		return err
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.cols, ts.rows = cols, rows
+	if ts.recorder != nil {
+		ts.recorder.resize(cols, rows)
+	}
+	return nil
+}
+
+// signal 向会话的前台进程组或整个会话发送信号
+//
+// 后端能直接发送信号时（本地 PTY、SSH）由后端处理；否则对前台进程组的
+// SIGINT、SIGQUIT、SIGTSTP 通过向 PTY 写入控制字符实现，由终端行规程转换为信号。
+func (ts *TerminalSession) signal(name, target string) (*models.SignalResult, error) {
+
+	name = normalizeSignalName(name)
+	if target == "" {
+		target = SignalTargetForeground
+	}
+	if target != SignalTargetForeground && target != SignalTargetSession {
+		return nil, fmt.Errorf("无效的信号目标: %s", target)
+	}
+
+	ts.mu.RLock()
+	backend, exited := ts.backend, ts.exitStatus != nil
+	ts.mu.RUnlock()
+
+	if backend == nil || exited {
+		return nil, fmt.Errorf("会话 %s 没有可接收信号的进程", ts.ID)
+	}
+
+	result := &models.SignalResult{SessionID: ts.ID, Signal: name, Target: target}
+	if signaler, ok := backend.(signalBackend); ok {
+		method, pgids, err := signaler.Signal(name, target)
+		if err == nil {
+			result.Method, result.Pgids = method, pgids
+			log.Printf("会话 %s: 已发送 %s（%s，%s）", ts.ID, name, target, method)
+			return result, nil
+		}
+		if !errors.Is(err, errSignalUnsupported) {
+			return nil, err
+		}
+	}
+
+	char, ok := signalChars[name]
+	if !ok || target != SignalTargetForeground {
+		return nil, fmt.Errorf("会话 %s 不支持向%s发送 %s", ts.ID, signalTargetDesc(target), name)
+	}
+	if _, err := backend.Write([]byte{char}); err != nil {
+		return nil, err
+	}
+	result.Method = signalMethodTTY
+	return result, nil
+}
+
+// signalTargetDesc 返回信号目标的中文描述
+func signalTargetDesc(target string) string {
+
+	if target == SignalTargetSession {
+		return "整个会话"
+	}
+	return "前台进程组"
+}
+
+// normalizeSignalName 将 int、INT、SIGINT 统一为 SIGINT
+func normalizeSignalName(name string) string {
+
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	return name
+}
+
+// setTitle 设置会话标题并通知所有 v1 客户端
+func (ts *TerminalSession) setTitle(title string) {
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.Title = title
+	for client := range ts.clients {
+		client.sendControl(msgSetTitle, titleData{Title: title})
+	}
+}
+
+// notifyExit 向所有 v1 客户端发送进程退出状态
+func (ts *TerminalSession) notifyExit(status ExitStatus) {
+
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	for client := range ts.clients {
+		client.sendControl(msgExitStatus, status)
+	}
+}
+
+// startRecording 开始将会话录制到 path
+func (ts *TerminalSession) startRecording(path string, env map[string]string) error {
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.closed {
+		return fmt.Errorf("会话 %s 已关闭", ts.ID)
+	}
+	if ts.recorder != nil {
+		return fmt.Errorf("会话 %s 正在录制", ts.ID)
+	}
+
+	rec, err := newRecorder(path, ts.cols, ts.rows, ts.ID, env)
+	if err != nil {
+		return err
+	}
+	ts.recorder = rec
+	return nil
+}
+
+// stopRecording 停止录制并返回录像路径
+func (ts *TerminalSession) stopRecording() (string, error) {
+
+	ts.mu.Lock()
+	rec := ts.recorder
+	ts.recorder = nil
+	ts.mu.Unlock()
+
+	if rec == nil {
+		return "", fmt.Errorf("会话 %s 未在录制", ts.ID)
+	}
+	return rec.Path, rec.Close()
+}
+
+// close 关闭会话
+//
+// 在锁内标记关闭并取出后端与录像，锁外终止进程与保存录像：
+// 关闭后端可能要等待远端响应，不能阻塞其他读取会话状态的调用。
+func (ts *TerminalSession) close() {
+
+	ts.mu.Lock()
+	if ts.closed {
+		ts.mu.Unlock()
+		return
+	}
+
+	ts.closed = true
+	close(ts.done)
+	backend, rec := ts.backend, ts.recorder
+	ts.backend, ts.recorder = nil, nil
+
+	// 断开所有附加的客户端，已排队的输出与错误消息先发送完
+	for client := range ts.clients {
+		client.closeWith(websocket.CloseNormalClosure, "session closed")
+	}
+	ts.clients = make(map[*wsClient]struct{})
+	ts.mu.Unlock()
+
+	// 关闭 PTY 并终止进程
+	if backend != nil {
+		backend.Close()
+	}
+
+	// 结束录制
+	if rec != nil {
+		if err := rec.Close(); err != nil {
+			log.Printf("会话 %s 保存录像失败: %v", ts.ID, err)
+		}
+	}
+}
+
+// startProcess 启动终端进程（统一使用 PTY，包括 Windows）
+func (ts *TerminalSession) startProcess(terminal *models.Terminal) error {
+
+	// 解析 shell 与参数，Args[0] 为 shell 本身
+	args := append([]string{terminal.Shell}, strings.Fields(terminal.Params)...)
+	// 登录 shell 按惯例以 - 开头的程序名启动，Windows 上没有登录 shell 的概念
+	if terminal.Login && runtime.GOOS != "windows" {
+		args[0] = "-" + filepath.Base(terminal.Shell)
+	}
+
+	// 生成 PTY 启动参数，保存以便重启时复用
+	ts.spec = &spawnSpec{
+		Path: terminal.Shell,
+		Args: args,
+		Dir:  terminal.Cwd,
+		Env:  envList(terminal.Env),
+		Cols: 120,
+		Rows: 20,
+		// 每次启动都按配置创建新的 cgroup
+		Limits: terminal.Limits,
+	}
+	ts.cols, ts.rows = ts.spec.Cols, ts.spec.Rows
+	ts.Cwd = terminal.Cwd
+	ts.shellDesc = terminal.Shell
+	ts.launch = func(cols, rows uint16) (sessionBackend, error) {
+		spec := *ts.spec
+		spec.Cols, spec.Rows = cols, rows
+		backend, err := startLocalPTY(&spec)
+		if err != nil {
+			return nil, err
+		}
+		return backend, nil
+	}
+
+	return ts.spawn()
+}
+
+// spawn 按当前尺寸启动会话后端并开始读取输出
+//
+// 启动后端可能需要建立 SSH 连接或调用容器 API，调用方不能持有 mu，
+// 只在安装后端时加锁。启动期间会话被关闭时丢弃新的后端。
+func (ts *TerminalSession) spawn() error {
+
+	ts.mu.RLock()
+	cols, rows := ts.cols, ts.rows
+	ts.mu.RUnlock()
+
+	backend, err := ts.launch(cols, rows)
+	if err != nil {
+		return err
+	}
+
+	ts.mu.Lock()
+	if ts.closed {
+		ts.mu.Unlock()
+		_ = backend.Close()
+		return fmt.Errorf("会话 %s 已关闭", ts.ID)
+	}
+	ts.backend = backend
+	ts.Process = backend.Process()
+	ts.exitStatus = nil
+	ts.mu.Unlock()
+
+	go ts.pump(backend)
+	return nil
+}
@@ -0,0 +1,206 @@
+package terminal
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"edex-ui-golang/internal/models"
+)
+
+// shareGrant 会话的只读分享授权
+type shareGrant struct {
+	Token     string
+	SessionID string
+	CreatedAt time.Time
+}
+
+// shareTable 分享令牌表
+type shareTable struct {
+	mu     sync.RWMutex
+	grants map[string]*shareGrant
+}
+
+// newShareTable 创建分享令牌表
+func newShareTable() *shareTable {
+
+	return &shareTable{grants: make(map[string]*shareGrant)}
+}
+
+// lookup 以常数时间比较查找分享令牌
+func (t *shareTable) lookup(token string) (*shareGrant, bool) {
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for key, grant := range t.grants {
+		if tokenMatches(key, token) {
+			return grant, true
+		}
+	}
+	return nil, false
+}
+
+// ShareSession 为会话生成只读分享令牌
+//
+// 持有该令牌的连接以观察者身份附加：接收输出，但输入与调整尺寸被丢弃。
+func (wsm *WebSocketManager) ShareSession(sessionID string) (*models.TerminalShare, error) {
+
+	session, exists := wsm.sessions.Get(sessionID)
+	if !exists {
+		return nil, fmt.Errorf("会话不存在: %s", sessionID)
+	}
+
+	token, err := newAuthToken()
+	if err != nil {
+		return nil, err
+	}
+
+	grant := &shareGrant{Token: token, SessionID: session.ID, CreatedAt: time.Now()}
+	wsm.shares.mu.Lock()
+	wsm.shares.grants[token] = grant
+	wsm.shares.mu.Unlock()
+
+	log.Printf("会话 %s 已生成只读分享令牌", session.ID)
+	return wsm.shareInfo(grant), nil
+}
+
+// ListShares 列出会话的所有分享令牌
+func (wsm *WebSocketManager) ListShares(sessionID string) []models.TerminalShare {
+
+	wsm.shares.mu.RLock()
+	defer wsm.shares.mu.RUnlock()
+
+	shares := []models.TerminalShare{}
+	for _, grant := range wsm.shares.grants {
+		if grant.SessionID == sessionID {
+			shares = append(shares, *wsm.shareInfo(grant))
+		}
+	}
+	return shares
+}
+
+// RevokeShare 撤销分享令牌并断开通过它连接的观察者
+func (wsm *WebSocketManager) RevokeShare(token string) error {
+
+	grant, exists := wsm.shares.lookup(token)
+	if !exists {
+		return fmt.Errorf("分享令牌不存在")
+	}
+
+	wsm.shares.mu.Lock()
+	delete(wsm.shares.grants, grant.Token)
+	wsm.shares.mu.Unlock()
+
+	if session, exists := wsm.sessions.Get(grant.SessionID); exists {
+		session.disconnectViewers(grant.Token)
+	}
+
+	log.Printf("会话 %s 的分享令牌已撤销", grant.SessionID)
+	return nil
+}
+
+// revokeSessionShares 会话关闭时删除其所有分享令牌
+func (wsm *WebSocketManager) revokeSessionShares(sessionID string) {
+
+	wsm.shares.mu.Lock()
+	defer wsm.shares.mu.Unlock()
+
+	for token, grant := range wsm.shares.grants {
+		if grant.SessionID == sessionID {
+			delete(wsm.shares.grants, token)
+		}
+	}
+}
+
+// shareInfo 生成分享信息
+//
+// 链接中的地址取自服务器实际监听的地址：监听所有网卡时使用本机的局域网地址，
+// 只监听回环地址时链接仅本机可用，由 LocalOnly 标明。
+func (wsm *WebSocketManager) shareInfo(grant *shareGrant) *models.TerminalShare {
+
+	host, port := loopbackHost, 0
+	if wsm.manager.terminal != nil {
+		port = wsm.manager.terminal.Port
+	}
+	if addr := wsm.addr.Load(); addr != nil {
+		port = addr.Port
+		switch {
+		case addr.IP.IsUnspecified():
+			if lan := lanAddress(); lan != "" {
+				host = lan
+			}
+		case !addr.IP.IsLoopback():
+			host = addr.IP.String()
+		}
+	}
+
+	return &models.TerminalShare{
+		SessionID: grant.SessionID,
+		Token:     grant.Token,
+		URL:       fmt.Sprintf("ws://%s/webterminal?share=%s", net.JoinHostPort(host, strconv.Itoa(port)), grant.Token),
+		LocalOnly: net.ParseIP(host).IsLoopback(),
+		CreatedAt: grant.CreatedAt.Unix(),
+	}
+}
+
+// lanAddress 返回第一个已启用的非回环网卡的 IPv4 地址，没有时返回空串
+func lanAddress() string {
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil && !ipNet.IP.IsLinkLocalUnicast() {
+				return ipNet.IP.String()
+			}
+		}
+	}
+	return ""
+}
+
+// viewers 返回会话中以只读方式附加的客户端
+func (ts *TerminalSession) viewers() []models.TerminalViewer {
+
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	viewers := []models.TerminalViewer{}
+	for client := range ts.clients {
+		if !client.readOnly {
+			continue
+		}
+		viewers = append(viewers, models.TerminalViewer{
+			ID:          client.id,
+			RemoteAddr:  client.remoteAddr,
+			ConnectedAt: client.connectedAt.Unix(),
+		})
+	}
+	return viewers
+}
+
+// disconnectViewers 断开通过指定分享令牌连接的观察者
+func (ts *TerminalSession) disconnectViewers(token string) {
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	for client := range ts.clients {
+		if client.readOnly && client.shareToken == token {
+			client.shutdown()
+			delete(ts.clients, client)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package terminal
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"edex-ui-golang/internal/models"
+)
+
+func TestShareInfoUsesBindAddress(t *testing.T) {
+
+	grant := &shareGrant{Token: "tok", SessionID: "s1", CreatedAt: time.Now()}
+
+	tests := []struct {
+		name          string
+		ip            net.IP
+		wantLocalOnly bool
+		wantHost      string
+	}{
+		{"loopback", net.ParseIP("127.0.0.1"), true, "127.0.0.1:7000"},
+		{"specific address", net.ParseIP("192.168.1.20"), false, "192.168.1.20:7000"},
+	}
+	for _, tt := range tests {
+		wsm := NewWebSocketManager(NewManager(&models.Settings{}))
+		wsm.addr.Store(&net.TCPAddr{IP: tt.ip, Port: 7000})
+
+		share := wsm.shareInfo(grant)
+		if share.LocalOnly != tt.wantLocalOnly {
+			t.Errorf("%s: LocalOnly = %v, want %v", tt.name, share.LocalOnly, tt.wantLocalOnly)
+		}
+		if want := "ws://" + tt.wantHost + "/webterminal?share=tok"; share.URL != want {
+			t.Errorf("%s: URL = %q, want %q", tt.name, share.URL, want)
+		}
+	}
+
+	// 监听所有网卡时链接中不能出现 0.0.0.0
+	wsm := NewWebSocketManager(NewManager(&models.Settings{}))
+	wsm.addr.Store(&net.TCPAddr{IP: net.IPv4zero, Port: 7000})
+	share := wsm.shareInfo(grant)
+	if strings.Contains(share.URL, "0.0.0.0") {
+		t.Errorf("URL = %q, want a reachable address", share.URL)
+	}
+	if lan := lanAddress(); lan != "" && share.LocalOnly {
+		t.Errorf("LocalOnly = true with LAN address %s", lan)
+	}
+}
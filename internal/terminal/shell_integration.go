@@ -0,0 +1,134 @@
+package terminal
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"edex-ui-golang/internal/models"
+	"edex-ui-golang/internal/utils"
+)
+
+// shellIntegrationFS 内置的 shell 集成脚本，输出 OSC 133 命令标记与 OSC 7 工作目录
+//
+//go:embed shellintegration/edex.*
+var shellIntegrationFS embed.FS
+
+// shellIntegrationDirName 释放脚本的子目录
+const shellIntegrationDirName = "shell-integration"
+
+// shellIntegrationRC 各 shell 的配置文件与加载方式
+var shellIntegrationRC = map[string]struct {
+	rcFile string
+	source string
+}{
+	"bash": {"~/.bashrc", `[ "$TERM_PROGRAM" = "eDEX-UI" ] && source %q`},
+	"zsh":  {"~/.zshrc", `[[ "$TERM_PROGRAM" == "eDEX-UI" ]] && source %q`},
+	"fish": {"~/.config/fish/config.fish", `test "$TERM_PROGRAM" = "eDEX-UI"; and source %q`},
+}
+
+// shellIntegrationName 根据 shell 路径或名称得到脚本对应的 shell
+func shellIntegrationName(shell string) (string, error) {
+
+	name := strings.TrimSuffix(filepath.Base(shell), ".exe")
+	if _, ok := shellIntegrationRC[name]; !ok {
+		return "", fmt.Errorf("不支持的 shell: %s（支持 bash、zsh、fish）", shell)
+	}
+	return name, nil
+}
+
+// installShellIntegration 将内置脚本释放到程序目录，已存在的文件会被覆盖以保持最新
+func installShellIntegration() (string, error) {
+
+	appDir, err := utils.GetAppDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(appDir, shellIntegrationDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	for name := range shellIntegrationRC {
+		data, err := shellIntegrationFS.ReadFile("shellintegration/edex." + name)
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "edex."+name), data, 0644); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// GetShellIntegration 返回指定 shell 的集成脚本及需要加入配置文件的一行
+//
+// shell 为空时使用默认 shell 配置中的 shell。
+func (m *Manager) GetShellIntegration(shell string) (*models.ShellIntegration, error) {
+
+	if shell == "" {
+		profile, err := m.shellProfile("")
+		if err != nil {
+			return nil, err
+		}
+		shell = profile.Path
+	}
+	name, err := shellIntegrationName(shell)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := installShellIntegration()
+	if err != nil {
+		return nil, fmt.Errorf("释放 shell 集成脚本失败: %v", err)
+	}
+
+	script, err := shellIntegrationFS.ReadFile("shellintegration/edex." + name)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := shellIntegrationRC[name]
+	path := filepath.Join(dir, "edex."+name)
+	return &models.ShellIntegration{
+		Shell:   name,
+		Path:    path,
+		RCFile:  rc.rcFile,
+		Snippet: fmt.Sprintf(rc.source, path),
+		Script:  string(script),
+	}, nil
+}
+
+// ListCommands 返回会话中通过 shell 集成识别的命令
+func (m *Manager) ListCommands(sessionID string) ([]models.TerminalCommand, error) {
+
+	session, err := m.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session.commandList(), nil
+}
+
+// GetCommandOutput 返回一条命令的输出，index 小于 0 表示最近一条
+func (m *Manager) GetCommandOutput(sessionID string, index int) (*models.TerminalOutput, error) {
+
+	session, err := m.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd, err := session.findCommand(index)
+	if err != nil {
+		return nil, err
+	}
+
+	// 运行中的命令取到当前末尾
+	end := cmd.OutputEnd
+	if end < 0 {
+		end = 0
+	}
+	return m.GetOutputRange(sessionID, cmd.OutputStart, end)
+}
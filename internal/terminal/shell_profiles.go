@@ -0,0 +1,122 @@
+package terminal
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+
+	"edex-ui-golang/internal/models"
+)
+
+// ValidateShellProfile 校验 shell 配置，命令必须能在 PATH 中找到
+func ValidateShellProfile(profile models.ShellProfile) error {
+
+	_, err := resolveShellProfile(profile)
+	return err
+}
+
+// resolveShellProfile 校验 shell 配置并返回命令的完整路径
+func resolveShellProfile(profile models.ShellProfile) (string, error) {
+
+	if strings.TrimSpace(profile.Name) == "" {
+		return "", fmt.Errorf("shell 配置名称不能为空")
+	}
+	if strings.TrimSpace(profile.Command) == "" {
+		return "", fmt.Errorf("shell 配置的命令不能为空")
+	}
+	limits := profile.Limits
+	if limits.MemoryMB < 0 || limits.CPUPercent < 0 || limits.MaxProcesses < 0 || profile.IdleTimeout < 0 {
+		return "", fmt.Errorf("资源限制与空闲超时不能为负数")
+	}
+	for key := range profile.Env {
+		if key == "" || strings.ContainsAny(key, "=\x00") {
+			return "", fmt.Errorf("无效的环境变量名: %q", key)
+		}
+	}
+
+	path, err := exec.LookPath(profile.Command)
+	if err != nil {
+		return "", fmt.Errorf("找不到shell: %s", profile.Command)
+	}
+	return path, nil
+}
+
+// SetShellProfiles 加载 shell 配置，返回填充了路径与错误信息的配置列表
+//
+// 无效的配置会保留在列表中供用户修改，但不能用于新建会话；
+// 默认配置无效时改用第一个有效的配置。
+func (m *Manager) SetShellProfiles(profiles models.ShellProfiles) models.ShellProfiles {
+
+	resolved := models.ShellProfiles{
+		Default:  profiles.Default,
+		Profiles: make([]models.ShellProfile, 0, len(profiles.Profiles)),
+	}
+	defaultValid := false
+	firstValid := ""
+	for _, profile := range profiles.Profiles {
+		path, err := resolveShellProfile(profile)
+		if err != nil {
+			profile.Path, profile.Error = "", err.Error()
+			log.Printf("shell 配置 %s 无效: %v", profile.Name, err)
+		} else {
+			profile.Path, profile.Error = path, ""
+			if firstValid == "" {
+				firstValid = profile.ID
+			}
+			if profile.ID == profiles.Default {
+				defaultValid = true
+			}
+		}
+		resolved.Profiles = append(resolved.Profiles, profile)
+	}
+	if !defaultValid && firstValid != "" {
+		log.Printf("默认 shell 配置 %q 不可用，改用 %q", profiles.Default, firstValid)
+		resolved.Default = firstValid
+	}
+
+	m.mu.Lock()
+	m.shellProfiles = resolved
+	m.mu.Unlock()
+
+	return m.ShellProfiles()
+}
+
+// ShellProfiles 返回当前加载的 shell 配置
+func (m *Manager) ShellProfiles() models.ShellProfiles {
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	profiles := models.ShellProfiles{
+		Default:  m.shellProfiles.Default,
+		Profiles: make([]models.ShellProfile, len(m.shellProfiles.Profiles)),
+	}
+	copy(profiles.Profiles, m.shellProfiles.Profiles)
+	return profiles
+}
+
+// shellProfile 按 ID 查找可用的 shell 配置，ID 为空时返回默认配置
+//
+// 配置由 SetShellProfiles 加载，旧版 Shell 与 ShellArgs 设置在加载前已迁移为配置。
+func (m *Manager) shellProfile(id string) (models.ShellProfile, error) {
+
+	profiles := m.ShellProfiles()
+	if len(profiles.Profiles) == 0 {
+		return models.ShellProfile{}, fmt.Errorf("尚未加载 shell 配置")
+	}
+
+	if id == "" {
+		id = profiles.Default
+	}
+	for _, profile := range profiles.Profiles {
+		if profile.ID != id {
+			continue
+		}
+		if profile.Error != "" {
+			return profile, fmt.Errorf("shell 配置 %s 无效: %s", profile.Name, profile.Error)
+		}
+		return profile, nil
+	}
+	return models.ShellProfile{}, fmt.Errorf("shell 配置不存在: %s", id)
+}
@@ -0,0 +1,77 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+
+	"edex-ui-golang/internal/models"
+)
+
+func TestSetShellProfilesDefault(t *testing.T) {
+
+	valid := models.ShellProfile{ID: "sh", Name: "sh", Command: "sh"}
+	other := models.ShellProfile{ID: "other", Name: "other", Command: "sh", Args: "-i"}
+	missing := models.ShellProfile{ID: "missing", Name: "missing", Command: "edex-missing-shell"}
+	unnamed := models.ShellProfile{ID: "unnamed", Command: "sh"}
+
+	tests := []struct {
+		name        string
+		profiles    models.ShellProfiles
+		wantDefault string
+	}{
+		{"default valid", models.ShellProfiles{Default: "other", Profiles: []models.ShellProfile{valid, other}}, "other"},
+		{"default not found by LookPath", models.ShellProfiles{Default: "missing", Profiles: []models.ShellProfile{missing, other, valid}}, "other"},
+		{"default fails validation", models.ShellProfiles{Default: "unnamed", Profiles: []models.ShellProfile{unnamed, valid}}, "sh"},
+		{"default unknown", models.ShellProfiles{Default: "gone", Profiles: []models.ShellProfile{valid}}, "sh"},
+		{"nothing valid", models.ShellProfiles{Default: "missing", Profiles: []models.ShellProfile{missing}}, "missing"},
+	}
+	for _, tt := range tests {
+		m := NewManager(&models.Settings{})
+		resolved := m.SetShellProfiles(tt.profiles)
+		m.Close()
+
+		if resolved.Default != tt.wantDefault {
+			t.Errorf("%s: default = %q, want %q", tt.name, resolved.Default, tt.wantDefault)
+		}
+		// 无效的配置保留在列表中并带有错误信息
+		if len(resolved.Profiles) != len(tt.profiles.Profiles) {
+			t.Errorf("%s: %d profiles, want %d", tt.name, len(resolved.Profiles), len(tt.profiles.Profiles))
+		}
+		for _, p := range resolved.Profiles {
+			if (p.Error == "") == (p.Path == "") {
+				t.Errorf("%s: profile %s has path %q and error %q", tt.name, p.ID, p.Path, p.Error)
+			}
+		}
+	}
+}
+
+func TestShellProfileLookup(t *testing.T) {
+
+	m := NewManager(&models.Settings{Shell: "sh"})
+	defer m.Close()
+
+	// 旧版设置不再作为后备，配置必须先加载
+	if _, err := m.shellProfile(""); err == nil {
+		t.Error("default profile resolved before profiles were loaded")
+	}
+
+	m.SetShellProfiles(models.ShellProfiles{
+		Default: "missing",
+		Profiles: []models.ShellProfile{
+			{ID: "missing", Name: "missing", Command: "edex-missing-shell"},
+			{ID: "sh", Name: "sh", Command: "sh"},
+		},
+	})
+
+	// 默认配置的 shell 找不到时使用第一个有效的配置
+	profile, err := m.shellProfile("")
+	if err != nil || profile.ID != "sh" || profile.Path == "" {
+		t.Errorf("default profile = %+v, %v, want sh with resolved path", profile, err)
+	}
+	if _, err := m.shellProfile("missing"); err == nil || !strings.Contains(err.Error(), "找不到shell") {
+		t.Errorf("invalid profile error = %v", err)
+	}
+	if _, err := m.shellProfile("gone"); err == nil {
+		t.Error("unknown profile resolved")
+	}
+}
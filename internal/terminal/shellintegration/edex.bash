@@ -0,0 +1,59 @@
+# eDEX-UI shell integration for bash
+#
+# 在 ~/.bashrc 末尾加入（<eDEX-UI 目录> 为程序所在目录）：
+#   [ "$TERM_PROGRAM" = "eDEX-UI" ] && source <eDEX-UI 目录>/shell-integration/edex.bash
+#
+# 输出 OSC 133 命令标记与 OSC 7 工作目录通知。
+# bash 4.4 及以上通过 PS0 标记命令开始；更早的版本退而使用 DEBUG trap，会覆盖已有的 DEBUG trap。
+
+[[ $- == *i* ]] || return 0
+[[ -n "$EDEX_SHELL_INTEGRATION" ]] && return 0
+EDEX_SHELL_INTEGRATION=1
+
+__edex_status=0
+__edex_at_prompt=0
+__edex_in_command=0
+
+__edex_save_status() {
+    __edex_status=$?
+    # PROMPT_COMMAND 中其他钩子触发的 DEBUG trap 不算用户命令
+    __edex_at_prompt=0
+}
+
+__edex_prompt() {
+    if [[ $__edex_in_command == 1 ]]; then
+        printf '\e]133;D;%s\a' "$__edex_status"
+        __edex_in_command=0
+    fi
+    printf '\e]7;file://%s%s\a' "$HOSTNAME" "$PWD"
+    __edex_at_prompt=1
+}
+
+__edex_preexec() {
+    # 只在提示符之后的第一个命令触发，跳过补全与自身的钩子
+    [[ $__edex_at_prompt == 1 ]] || return
+    [[ -n "$COMP_LINE" ]] && return
+    [[ $BASH_COMMAND == __edex_* ]] && return
+    __edex_at_prompt=0
+    __edex_in_command=1
+    printf '\e]133;C\a'
+}
+
+# 已有的 PROMPT_COMMAND 可能以分号结尾，拼接前去掉，避免出现 ;;
+__edex_pc=$PROMPT_COMMAND
+while [[ $__edex_pc == *[\;[:space:]] ]]; do
+    __edex_pc=${__edex_pc%?}
+done
+PROMPT_COMMAND="__edex_save_status${__edex_pc:+;$__edex_pc};__edex_prompt"
+unset __edex_pc
+
+PS1="\[\e]133;A\a\]$PS1\[\e]133;B\a\]"
+
+if (( BASH_VERSINFO[0] > 4 || (BASH_VERSINFO[0] == 4 && BASH_VERSINFO[1] >= 4) )); then
+    # PS0 在读入非空命令之后、执行之前展开，不经过 readline，因此不使用 \[ \]；
+    # 数组下标中的赋值用于在当前 shell 中记录状态
+    __edex_blank=()
+    PS0="$PS0"'\e]133;C\a${__edex_blank[__edex_in_command=1]}'
+else
+    trap '__edex_preexec' DEBUG
+fi
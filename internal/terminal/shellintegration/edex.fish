@@ -0,0 +1,30 @@
+# eDEX-UI shell integration for fish
+#
+# 在 ~/.config/fish/config.fish 末尾加入（<eDEX-UI 目录> 为程序所在目录）：
+#   test "$TERM_PROGRAM" = "eDEX-UI"; and source <eDEX-UI 目录>/shell-integration/edex.fish
+#
+# 输出 OSC 133 命令标记与 OSC 7 工作目录通知。
+
+status is-interactive; or exit 0
+set -q EDEX_SHELL_INTEGRATION; and exit 0
+set -g EDEX_SHELL_INTEGRATION 1
+
+function __edex_prompt_start --on-event fish_prompt
+    printf '\e]7;file://%s%s\a' (hostname) "$PWD"
+    printf '\e]133;A\a'
+end
+
+function __edex_preexec --on-event fish_preexec
+    printf '\e]133;C\a'
+end
+
+function __edex_postexec --on-event fish_postexec
+    printf '\e]133;D;%s\a' $status
+end
+
+# 在原有提示符之后输出命令输入起点
+functions -q fish_prompt; and functions -c fish_prompt __edex_original_prompt
+function fish_prompt
+    functions -q __edex_original_prompt; and __edex_original_prompt
+    printf '\e]133;B\a'
+end
@@ -0,0 +1,84 @@
+package terminal
+
+import (
+	"log"
+	"time"
+
+	"edex-ui-golang/internal/models"
+)
+
+// ShutdownTimeout 程序退出时等待会话进程响应 SIGHUP 的最长时间
+const ShutdownTimeout = 3 * time.Second
+
+// settlePollInterval 等待会话进程退出时检查的间隔
+const settlePollInterval = 50 * time.Millisecond
+
+// hangup 通知会话进程挂断，返回收到挂断的后端
+//
+// 后端不支持挂断（SSH、串口等）时返回 nil，这类会话直接关闭即可。
+// shell 已退出的会话仍会通知留在会话中的后台作业。
+func (ts *TerminalSession) hangup() hangupBackend {
+
+	ts.mu.RLock()
+	backend, open := ts.backend, ts.backend != nil && !ts.closed
+	ts.mu.RUnlock()
+
+	hangupper, ok := backend.(hangupBackend)
+	if !open || !ok {
+		return nil
+	}
+	if err := hangupper.Hangup(); err != nil {
+		log.Printf("会话 %s: %v", ts.ID, err)
+	}
+	return hangupper
+}
+
+// waitSettled 等待会话中的所有进程退出，超过 deadline 返回 false
+//
+// shell 退出后继续等待后台作业，忽略 SIGHUP 的进程会一直留到被强制终止。
+func waitSettled(backend hangupBackend, deadline time.Time) bool {
+
+	for backend.Lingering() {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(settlePollInterval)
+	}
+	return true
+}
+
+// closeAllSessions 结束所有会话
+//
+// 先向所有本地会话的进程组发送 SIGHUP，让 shell 与编辑器等程序有机会保存状态；
+// timeout 后仍有进程存活的会话（包括 shell 已退出、后台作业忽略了 SIGHUP 的情况）
+// 在关闭时由后端按会话 ID 以 SIGKILL 终止，并记入报告。关闭会话时录像会写入磁盘。
+func (wsm *WebSocketManager) closeAllSessions(timeout time.Duration) models.ShutdownReport {
+
+	sessions := wsm.sessions.List()
+	report := models.ShutdownReport{
+		Sessions: len(sessions),
+		Busy:     []models.TerminalSessionInfo{},
+		Killed:   []string{},
+	}
+
+	waits := make(map[*TerminalSession]hangupBackend, len(sessions))
+	for _, session := range sessions {
+		if info := session.info(); info.Busy {
+			log.Printf("会话 %s 退出时仍在运行: %s", session.ID, info.Process)
+			report.Busy = append(report.Busy, info)
+		}
+		if backend := session.hangup(); backend != nil {
+			waits[session] = backend
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for _, session := range sessions {
+		if backend, ok := waits[session]; ok && !waitSettled(backend, deadline) {
+			log.Printf("会话 %s 中的进程未响应 SIGHUP，强制终止", session.ID)
+			report.Killed = append(report.Killed, session.ID)
+		}
+		wsm.removeSession(session)
+	}
+	return report
+}
@@ -0,0 +1,88 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"edex-ui-golang/internal/models"
+)
+
+// processGone 判断进程是否已退出，无人回收的僵尸进程视为已退出
+func processGone(pid int) bool {
+
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return true
+	}
+	end := strings.LastIndexByte(string(data), ')')
+	return end >= 0 && strings.HasPrefix(strings.TrimSpace(string(data[end+1:])), "Z")
+}
+
+func TestCloseAllSessionsKillsProcessesIgnoringHangup(t *testing.T) {
+
+	m := NewManager(&models.Settings{})
+	defer m.Close()
+
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	// 后台作业设置好忽略 SIGHUP 后才写入 PID，shell 等到 PID 写入后再继续
+	job := `sh -c 'trap "" HUP; echo $$ > ` + pidFile + `; exec sleep 60' & while [ ! -s ` + pidFile + ` ]; do sleep 0.05; done; `
+	tests := []struct {
+		name   string
+		script string
+	}{
+		// shell 仍在运行，后台作业忽略 SIGHUP
+		{"running", job + "wait"},
+		// shell 已退出，后台作业留在会话中
+		{"exited", job + "exit 0"},
+	}
+	for _, tt := range tests {
+		_ = os.Remove(pidFile)
+
+		session := newTerminalSession(tt.name, 1024)
+		session.cols, session.rows = 80, 24
+		session.launch = func(cols, rows uint16) (sessionBackend, error) {
+			return startLocalPTY(&spawnSpec{Path: "/bin/sh", Args: []string{"sh", "-c", tt.script}, Cols: cols, Rows: rows})
+		}
+		if err := session.spawn(); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.websocketManager.sessions.add(session); err != nil {
+			t.Fatal(err)
+		}
+
+		var pid int
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			data, _ := os.ReadFile(pidFile)
+			if n, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+				pid = n
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: background job did not start", tt.name)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		if tt.name == "exited" {
+			session.backend.Wait()
+		}
+
+		report := m.websocketManager.closeAllSessions(300 * time.Millisecond)
+		if !slices.Contains(report.Killed, tt.name) {
+			t.Errorf("%s: killed = %v, want session reported", tt.name, report.Killed)
+		}
+
+		deadline = time.Now().Add(2 * time.Second)
+		for !processGone(pid) {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: process %d survived shutdown", tt.name, pid)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+}
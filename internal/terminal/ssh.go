@@ -0,0 +1,396 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"edex-ui-golang/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// SSH 连接参数
+const (
+	sshDialTimeout        = 10 * time.Second
+	defaultSSHPort        = 22
+	defaultSSHKeepAlive   = 30 * time.Second
+	sshKeepAliveMaxMisses = 3
+	sshReconnectAttempts  = 10
+	sshReconnectMaxDelay  = 30 * time.Second
+)
+
+// sshBackend 通过内置 SSH 客户端连接的远程 shell
+//
+// 连接中断时（保活超时、网络错误）按配置自动重连并启动新的 shell；
+// 远程 shell 正常退出时后端结束，退出码与本地进程一样交给会话处理。
+// 所有连接的输出都写入同一个管道，因此对会话而言重连是透明的。
+type sshBackend struct {
+	profile models.SSHProfile
+	addr    string
+
+	reader *io.PipeReader
+	writer *io.PipeWriter
+
+	mu      sync.Mutex
+	client  *ssh.Client
+	session *ssh.Session
+	stdin   io.WriteCloser
+	cols    uint16
+	rows    uint16
+	closed  bool
+
+	// Close 时关闭，用于中断重连等待
+	closing   chan struct{}
+	closeOnce sync.Once
+	// 后端结束时关闭，之后 status 可读
+	exited chan struct{}
+	status ExitStatus
+}
+
+// ValidateSSHProfile 校验 SSH 主机配置
+func ValidateSSHProfile(profile models.SSHProfile) error {
+
+	if strings.TrimSpace(profile.Host) == "" {
+		return fmt.Errorf("SSH 主机不能为空")
+	}
+	if strings.TrimSpace(profile.User) == "" {
+		return fmt.Errorf("SSH 用户名不能为空")
+	}
+	if profile.Port < 0 || profile.Port > 65535 {
+		return fmt.Errorf("无效的 SSH 端口: %d", profile.Port)
+	}
+	return nil
+}
+
+// sshAddress 返回 host:port 形式的地址
+func sshAddress(profile models.SSHProfile) string {
+
+	port := profile.Port
+	if port == 0 {
+		port = defaultSSHPort
+	}
+	return net.JoinHostPort(profile.Host, strconv.Itoa(port))
+}
+
+// sshKeepAlive 返回保活间隔，0 表示关闭
+func sshKeepAlive(profile models.SSHProfile) time.Duration {
+
+	switch {
+	case profile.KeepAlive < 0:
+		return 0
+	case profile.KeepAlive == 0:
+		return defaultSSHKeepAlive
+	default:
+		return time.Duration(profile.KeepAlive) * time.Second
+	}
+}
+
+// startSSH 连接远程主机并启动 shell
+//
+// 首次连接同步完成，认证或主机密钥校验失败直接返回给调用方。
+func startSSH(profile models.SSHProfile, cols, rows uint16) (*sshBackend, error) {
+
+	if err := ValidateSSHProfile(profile); err != nil {
+		return nil, err
+	}
+
+	reader, writer := io.Pipe()
+	b := &sshBackend{
+		profile: profile,
+		addr:    sshAddress(profile),
+		reader:  reader,
+		writer:  writer,
+		cols:    cols,
+		rows:    rows,
+		closing: make(chan struct{}),
+		exited:  make(chan struct{}),
+	}
+
+	if err := b.connect(); err != nil {
+		return nil, err
+	}
+	go b.run()
+	return b, nil
+}
+
+// connect 建立连接、申请 PTY 并启动远程 shell
+func (b *sshBackend) connect() error {
+
+	client, err := dialSSH(b.profile)
+	if err != nil {
+		return err
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return fmt.Errorf("创建 SSH 会话失败: %v", err)
+	}
+
+	b.mu.Lock()
+	cols, rows := b.cols, b.rows
+	b.mu.Unlock()
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	if err := session.RequestPty("xterm-256color", int(rows), int(cols), modes); err != nil {
+		client.Close()
+		return fmt.Errorf("申请远程 PTY 失败: %v", err)
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		client.Close()
+		return err
+	}
+	session.Stdout = b.writer
+	session.Stderr = b.writer
+
+	if err := session.Shell(); err != nil {
+		client.Close()
+		return fmt.Errorf("启动远程 shell 失败: %v", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		client.Close()
+		return fmt.Errorf("SSH 会话已关闭")
+	}
+	b.client, b.session, b.stdin = client, session, stdin
+	return nil
+}
+
+// run 等待远程 shell 结束，连接中断时按配置重连
+func (b *sshBackend) run() {
+
+	for {
+		b.mu.Lock()
+		client, session := b.client, b.session
+		b.mu.Unlock()
+
+		stopKeepAlive := make(chan struct{})
+		if interval := sshKeepAlive(b.profile); interval > 0 {
+			go b.keepAlive(client, interval, stopKeepAlive)
+		}
+		err := session.Wait()
+		close(stopKeepAlive)
+		client.Close()
+
+		// 重连期间的尺寸调整只记录下来，由下一次 connect 申请 PTY 时使用
+		b.mu.Lock()
+		b.stdin, b.session = nil, nil
+		closed := b.closed
+		b.mu.Unlock()
+
+		if closed {
+			b.finish(ExitStatus{Code: -1})
+			return
+		}
+		if status, exited := sshExitStatus(err); exited {
+			b.finish(status)
+			return
+		}
+
+		log.Printf("SSH 连接 %s 已断开: %v", b.addr, err)
+		if !b.profile.Reconnect || !b.reconnect() {
+			b.notice("[SSH 连接已断开]")
+			b.finish(ExitStatus{Code: -1})
+			return
+		}
+	}
+}
+
+// sshExitStatus 判断 Wait 的结果是否为远程 shell 正常退出
+func sshExitStatus(err error) (ExitStatus, bool) {
+
+	if err == nil {
+		return ExitStatus{Code: 0}, true
+	}
+
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.Signal() != "" {
+			return ExitStatus{Code: -1, Signal: "SIG" + exitErr.Signal()}, true
+		}
+		return ExitStatus{Code: exitErr.ExitStatus()}, true
+	}
+
+	// ExitMissingError 与网络错误都表示连接在 shell 退出前中断
+	return ExitStatus{}, false
+}
+
+// reconnect 以指数退避重试连接，成功返回 true
+func (b *sshBackend) reconnect() bool {
+
+	delay := time.Second
+	for attempt := 1; attempt <= sshReconnectAttempts; attempt++ {
+		b.notice(fmt.Sprintf("[SSH 连接已断开，%d 秒后第 %d 次重连…]", int(delay.Seconds()), attempt))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-b.closing:
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+
+		err := b.connect()
+		if err == nil {
+			log.Printf("SSH 连接 %s 已恢复", b.addr)
+			b.notice("[已重新连接，远程 shell 已重新启动]")
+			return true
+		}
+		log.Printf("SSH 重连 %s 失败: %v", b.addr, err)
+
+		delay *= 2
+		if delay > sshReconnectMaxDelay {
+			delay = sshReconnectMaxDelay
+		}
+	}
+	return false
+}
+
+// keepAlive 定期发送保活请求，连续多次无响应时关闭连接以触发重连
+func (b *sshBackend) keepAlive(client *ssh.Client, interval time.Duration, stop chan struct{}) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	misses := 0
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		replied := make(chan error, 1)
+		go func() {
+			// 服务器不认识该请求时会回复失败，同样说明连接正常
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			replied <- err
+		}()
+
+		select {
+		case <-stop:
+			return
+		case err := <-replied:
+			if err != nil {
+				client.Close()
+				return
+			}
+			misses = 0
+		case <-time.After(interval):
+			misses++
+			if misses >= sshKeepAliveMaxMisses {
+				log.Printf("SSH 连接 %s 保活超时", b.addr)
+				client.Close()
+				return
+			}
+		}
+	}
+}
+
+// notice 在终端中显示连接状态提示
+func (b *sshBackend) notice(text string) {
+	_, _ = b.writer.Write([]byte("\r\n\x1b[0m" + text + "\r\n"))
+}
+
+// finish 记录退出状态并结束输出
+func (b *sshBackend) finish(status ExitStatus) {
+
+	b.status = status
+	close(b.exited)
+	b.writer.Close()
+}
+
+func (b *sshBackend) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
+
+// Write 写入远程 shell，重连期间的输入被丢弃
+func (b *sshBackend) Write(p []byte) (int, error) {
+
+	b.mu.Lock()
+	stdin := b.stdin
+	b.mu.Unlock()
+
+	if stdin == nil {
+		return len(p), nil
+	}
+	return stdin.Write(p)
+}
+
+// Resize 调整远程 PTY 尺寸，重连时沿用最新尺寸
+func (b *sshBackend) Resize(rows, cols uint16) error {
+
+	b.mu.Lock()
+	b.rows, b.cols = rows, cols
+	session := b.session
+	b.mu.Unlock()
+
+	if session == nil {
+		return nil
+	}
+	return session.WindowChange(int(rows), int(cols))
+}
+
+// Close 断开连接，不再重连
+func (b *sshBackend) Close() error {
+
+	b.mu.Lock()
+	b.closed = true
+	client := b.client
+	b.mu.Unlock()
+
+	b.closeOnce.Do(func() { close(b.closing) })
+	if client != nil {
+		client.Close()
+	}
+	return b.reader.Close()
+}
+
+// Signal 通过 SSH 请求向远程 shell 发送信号
+//
+// 服务端只会把信号交给 shell 本身，发给前台作业的信号改由会话写入控制字符。
+func (b *sshBackend) Signal(name, target string) (string, []int, error) {
+
+	if target != SignalTargetSession {
+		return "", nil, errSignalUnsupported
+	}
+
+	b.mu.Lock()
+	session := b.session
+	b.mu.Unlock()
+
+	if session == nil {
+		return "", nil, fmt.Errorf("SSH 连接尚未建立")
+	}
+	if err := session.Signal(ssh.Signal(strings.TrimPrefix(name, "SIG"))); err != nil {
+		return "", nil, fmt.Errorf("发送信号失败: %v", err)
+	}
+	return signalMethodRemote, nil, nil
+}
+
+// Wait 等待远程 shell 结束
+func (b *sshBackend) Wait() ExitStatus {
+
+	<-b.exited
+	return b.status
+}
+
+// Process 远程 shell 没有本地进程
+func (b *sshBackend) Process() *exec.Cmd {
+	return nil
+}
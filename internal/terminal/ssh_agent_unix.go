@@ -0,0 +1,19 @@
+//go:build !windows
+
+package terminal
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// dialSSHAgent 通过 SSH_AUTH_SOCK 连接 ssh-agent
+func dialSSHAgent() (net.Conn, error) {
+
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, fmt.Errorf("未设置 SSH_AUTH_SOCK")
+	}
+	return net.Dial("unix", socket)
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package terminal
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// dialSSHAgent 连接 ssh-agent
+//
+// 仅支持以 unix socket 提供的 agent（SSH_AUTH_SOCK），Windows OpenSSH 的命名管道 agent 暂不支持。
+func dialSSHAgent() (net.Conn, error) {
+
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, fmt.Errorf("未设置 SSH_AUTH_SOCK，Windows OpenSSH 的命名管道 agent 暂不支持")
+	}
+	return net.Dial("unix", socket)
+}
@@ -0,0 +1,320 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"edex-ui-golang/internal/models"
+	"edex-ui-golang/internal/utils"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// knownHostsFileName 程序目录中由 eDEX-UI 维护的 known_hosts，记录用户在界面中信任的主机
+const knownHostsFileName = "known_hosts"
+
+// defaultSSHKeyFiles 未指定私钥且未使用 agent 时尝试的私钥文件
+var defaultSSHKeyFiles = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// sshKnownHostsFiles 返回参与主机密钥校验的 known_hosts 文件，不存在的文件被忽略
+//
+// 包括用户的 ~/.ssh/known_hosts 与程序目录中的 known_hosts。
+var sshKnownHostsFiles = func() []string {
+
+	var files []string
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".ssh", "known_hosts"))
+	}
+	if path, err := appKnownHostsFile(); err == nil {
+		files = append(files, path)
+	}
+	return files
+}
+
+// appKnownHostsFile 返回程序维护的 known_hosts 路径
+func appKnownHostsFile() (string, error) {
+
+	appDir, err := utils.GetAppDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(appDir, knownHostsFileName), nil
+}
+
+// hostKeyCallback 基于 known_hosts 的主机密钥校验
+func hostKeyCallback() (ssh.HostKeyCallback, error) {
+
+	var files []string
+	for _, file := range sshKnownHostsFiles() {
+		if _, err := os.Stat(file); err == nil {
+			files = append(files, file)
+		}
+	}
+	// 没有任何文件时得到空数据库，所有主机都视为未知
+	return knownhosts.New(files...)
+}
+
+// dialSSH 连接并认证，返回 SSH 客户端
+func dialSSH(profile models.SSHProfile) (*ssh.Client, error) {
+
+	auth, cleanup, err := sshAuthMethods(profile)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	check, err := hostKeyCallback()
+	if err != nil {
+		return nil, fmt.Errorf("读取 known_hosts 失败: %v", err)
+	}
+
+	addr := sshAddress(profile)
+	var presented ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User: profile.User,
+		Auth: auth,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			presented = key
+			return check(hostname, remote, key)
+		},
+		Timeout: sshDialTimeout,
+	}
+
+	client, err := ssh.Dial("tcp", addr, config)
+
+	// 服务器优先提供的密钥类型可能不同于 known_hosts 中记录的类型，限定为已记录的类型重试一次
+	var keyErr *knownhosts.KeyError
+	if errors.As(err, &keyErr) && len(keyErr.Want) > 0 && !knownKeyType(keyErr.Want, presented) {
+		config.HostKeyAlgorithms = hostKeyAlgorithms(keyErr.Want)
+		client, err = ssh.Dial("tcp", addr, config)
+	}
+
+	if err != nil {
+		return nil, describeSSHError(addr, presented, err)
+	}
+	return client, nil
+}
+
+// knownKeyType known_hosts 中是否记录了与服务器提供的密钥相同类型的密钥
+func knownKeyType(want []knownhosts.KnownKey, key ssh.PublicKey) bool {
+
+	if key == nil {
+		return false
+	}
+	for _, known := range want {
+		if known.Key.Type() == key.Type() {
+			return true
+		}
+	}
+	return false
+}
+
+// hostKeyAlgorithms 由已记录的密钥类型得到可协商的主机密钥算法
+func hostKeyAlgorithms(want []knownhosts.KnownKey) []string {
+
+	var algorithms []string
+	seen := make(map[string]bool)
+	for _, known := range want {
+		keyType := known.Key.Type()
+		candidates := []string{keyType}
+		if keyType == ssh.KeyAlgoRSA {
+			candidates = []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+		}
+		for _, algorithm := range candidates {
+			if !seen[algorithm] {
+				seen[algorithm] = true
+				algorithms = append(algorithms, algorithm)
+			}
+		}
+	}
+	return algorithms
+}
+
+// describeSSHError 将连接错误转换为便于用户理解的描述
+func describeSSHError(addr string, presented ssh.PublicKey, err error) error {
+
+	var keyErr *knownhosts.KeyError
+	if errors.As(err, &keyErr) {
+		fingerprint := ""
+		if presented != nil {
+			fingerprint = ssh.FingerprintSHA256(presented)
+		}
+		if len(keyErr.Want) == 0 {
+			return fmt.Errorf("未知主机 %s（%s），请核对指纹后信任该主机", addr, fingerprint)
+		}
+		known := keyErr.Want[0]
+		return fmt.Errorf("主机 %s 的密钥 %s 与 %s:%d 中的记录不符，可能存在中间人攻击", addr, fingerprint, known.Filename, known.Line)
+	}
+
+	var revoked *knownhosts.RevokedError
+	if errors.As(err, &revoked) {
+		return fmt.Errorf("主机 %s 的密钥已被吊销", addr)
+	}
+	if strings.Contains(err.Error(), "unable to authenticate") {
+		return fmt.Errorf("SSH 认证失败 %s: %v", addr, err)
+	}
+	return fmt.Errorf("SSH 连接 %s 失败: %v", addr, err)
+}
+
+// sshAuthMethods 根据配置生成认证方式，返回的 cleanup 在握手结束后调用
+//
+// 不支持带口令的私钥与密码登录，此类场景请使用 ssh-agent。
+func sshAuthMethods(profile models.SSHProfile) ([]ssh.AuthMethod, func(), error) {
+
+	var methods []ssh.AuthMethod
+	cleanup := func() {}
+
+	if profile.KeyFile != "" {
+		signer, err := loadSSHKey(profile.KeyFile)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+
+	if profile.UseAgent || profile.KeyFile == "" {
+		conn, err := dialSSHAgent()
+		if err == nil {
+			cleanup = func() { conn.Close() }
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		} else if profile.UseAgent {
+			return nil, cleanup, fmt.Errorf("连接 ssh-agent 失败: %v", err)
+		}
+	}
+
+	// 未指定任何方式时，与 OpenSSH 一样尝试默认私钥
+	if profile.KeyFile == "" && !profile.UseAgent {
+		if home, err := os.UserHomeDir(); err == nil {
+			var signers []ssh.Signer
+			for _, name := range defaultSSHKeyFiles {
+				if signer, err := loadSSHKey(filepath.Join(home, ".ssh", name)); err == nil {
+					signers = append(signers, signer)
+				}
+			}
+			if len(signers) > 0 {
+				methods = append(methods, ssh.PublicKeys(signers...))
+			}
+		}
+	}
+
+	if len(methods) == 0 {
+		cleanup()
+		return nil, func() {}, fmt.Errorf("没有可用的 SSH 认证方式，请指定私钥文件或启动 ssh-agent")
+	}
+	return methods, cleanup, nil
+}
+
+// loadSSHKey 读取私钥文件
+func loadSSHKey(path string) (ssh.Signer, error) {
+
+	path, err := resolveCwd(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取私钥 %s 失败: %v", path, err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return nil, fmt.Errorf("私钥 %s 受口令保护，请将其加入 ssh-agent 后使用 agent 认证", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥 %s 失败: %v", path, err)
+	}
+	return signer, nil
+}
+
+// errHostKeyScanned 获取主机密钥时用于在认证前中止握手
+var errHostKeyScanned = errors.New("host key scanned")
+
+// fetchHostKey 连接主机并取得其主机密钥，不进行认证
+func fetchHostKey(profile models.SSHProfile) (string, ssh.PublicKey, error) {
+
+	if err := ValidateSSHProfile(profile); err != nil {
+		return "", nil, err
+	}
+
+	addr := sshAddress(profile)
+	var presented ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User: profile.User,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			presented = key
+			return errHostKeyScanned
+		},
+		Timeout: sshDialTimeout,
+	}
+
+	if _, err := ssh.Dial("tcp", addr, config); !errors.Is(err, errHostKeyScanned) {
+		if err == nil {
+			err = fmt.Errorf("未收到主机密钥")
+		}
+		return addr, nil, fmt.Errorf("获取 %s 的主机密钥失败: %v", addr, err)
+	}
+	return addr, presented, nil
+}
+
+// ScanSSHHostKey 获取主机提供的密钥及其是否已被信任
+func ScanSSHHostKey(profile models.SSHProfile) (*models.SSHHostKey, error) {
+
+	addr, key, err := fetchHostKey(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	check, err := hostKeyCallback()
+	if err != nil {
+		return nil, fmt.Errorf("读取 known_hosts 失败: %v", err)
+	}
+	remote, _ := net.ResolveTCPAddr("tcp", addr)
+	if remote == nil {
+		remote = &net.TCPAddr{}
+	}
+
+	return &models.SSHHostKey{
+		Host:        addr,
+		Type:        key.Type(),
+		Fingerprint: ssh.FingerprintSHA256(key),
+		Known:       check(addr, remote, key) == nil,
+	}, nil
+}
+
+// TrustSSHHostKey 将主机密钥写入程序维护的 known_hosts
+//
+// fingerprint 为用户核对过的指纹，与重新获取的主机密钥不一致时拒绝写入。
+func TrustSSHHostKey(profile models.SSHProfile, fingerprint string) error {
+
+	addr, key, err := fetchHostKey(profile)
+	if err != nil {
+		return err
+	}
+	if ssh.FingerprintSHA256(key) != fingerprint {
+		return fmt.Errorf("主机 %s 的密钥指纹已变化，请重新核对", addr)
+	}
+
+	path, err := appKnownHostsFile()
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	line := knownhosts.Line([]string{knownhosts.Normalize(addr)}, key)
+	if _, err := file.WriteString(line + "\n"); err != nil {
+		return err
+	}
+	log.Printf("已信任主机 %s 的密钥 %s", addr, fingerprint)
+	return nil
+}
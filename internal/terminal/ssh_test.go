@@ -0,0 +1,570 @@
+package terminal
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"edex-ui-golang/internal/models"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testSSHServer 测试用的进程内 SSH 服务器，只监听回环地址
+type testSSHServer struct {
+	addr    string
+	hostKey ssh.Signer
+	config  *ssh.ServerConfig
+
+	// 非 nil 时 shell 启动后调用并关闭通道，否则回显输入
+	exit func(ch ssh.Channel)
+	// 为 true 时第一个连接不响应保活请求
+	dropFirstKeepAlive bool
+
+	conns   atomic.Int32
+	ptys    chan [2]uint32 // 每次 pty-req 的列数与行数
+	windows chan [2]uint32 // 每次 window-change 的列数与行数
+}
+
+// newTestSSHServer 启动服务器，configure 设置认证方式
+func newTestSSHServer(t *testing.T, configure func(*ssh.ServerConfig)) *testSSHServer {
+
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{}
+	config.AddHostKey(hostKey)
+	configure(config)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &testSSHServer{
+		addr:    listener.Addr().String(),
+		hostKey: hostKey,
+		config:  config,
+		ptys:    make(chan [2]uint32, 16),
+		windows: make(chan [2]uint32, 16),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	return s
+}
+
+// handle 处理一个客户端连接
+func (s *testSSHServer) handle(conn net.Conn) {
+
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer sconn.Close()
+
+	drop := s.dropFirstKeepAlive && s.conns.Add(1) == 1
+	go func() {
+		for req := range reqs {
+			if drop {
+				continue
+			}
+			if req.WantReply {
+				_ = req.Reply(false, nil)
+			}
+		}
+	}()
+
+	for newCh := range chans {
+		if newCh.ChannelType() != "session" {
+			_ = newCh.Reject(ssh.UnknownChannelType, newCh.ChannelType())
+			continue
+		}
+		ch, chReqs, err := newCh.Accept()
+		if err != nil {
+			return
+		}
+		go s.session(ch, chReqs)
+	}
+}
+
+// session 处理会话通道上的请求
+func (s *testSSHServer) session(ch ssh.Channel, reqs <-chan *ssh.Request) {
+
+	defer ch.Close()
+
+	for req := range reqs {
+		switch req.Type {
+		case "pty-req":
+			var pty struct {
+				Term                      string
+				Cols, Rows, Width, Height uint32
+				Modes                     string
+			}
+			if err := ssh.Unmarshal(req.Payload, &pty); err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			s.ptys <- [2]uint32{pty.Cols, pty.Rows}
+			_ = req.Reply(true, nil)
+		case "window-change":
+			var win struct{ Cols, Rows, Width, Height uint32 }
+			if err := ssh.Unmarshal(req.Payload, &win); err == nil {
+				s.windows <- [2]uint32{win.Cols, win.Rows}
+			}
+		case "shell":
+			_ = req.Reply(true, nil)
+			_, _ = ch.Write([]byte("welcome\r\n"))
+			if s.exit != nil {
+				s.exit(ch)
+				return
+			}
+			go func() { _, _ = io.Copy(ch, ch) }()
+		default:
+			if req.WantReply {
+				_ = req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+// profile 返回连接该服务器的配置，保活默认关闭
+func (s *testSSHServer) profile(t *testing.T) models.SSHProfile {
+
+	t.Helper()
+
+	host, port, err := net.SplitHostPort(s.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := strconv.Atoi(port)
+	return models.SSHProfile{Host: host, Port: p, User: "tester", KeepAlive: -1}
+}
+
+// useKnownHosts 让主机密钥校验只使用包含给定密钥的临时 known_hosts
+func useKnownHosts(t *testing.T, addr string, keys ...ssh.PublicKey) {
+
+	t.Helper()
+
+	var lines []string
+	for _, key := range keys {
+		lines = append(lines, knownhosts.Line([]string{knownhosts.Normalize(addr)}, key))
+	}
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := sshKnownHostsFiles
+	sshKnownHostsFiles = func() []string { return []string{path} }
+	t.Cleanup(func() { sshKnownHostsFiles = saved })
+}
+
+// newTestKey 生成客户端密钥，返回签名器与写入临时目录的私钥文件
+func newTestKey(t *testing.T) (ssh.Signer, string) {
+
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return signer, path
+}
+
+// allowKey 只接受给定公钥的认证
+func allowKey(key ssh.PublicKey) func(*ssh.ServerConfig) {
+
+	return func(config *ssh.ServerConfig) {
+		config.PublicKeyCallback = func(_ ssh.ConnMetadata, presented ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(presented.Marshal(), key.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown key")
+		}
+	}
+}
+
+// testOutput 在后台收集后端输出
+type testOutput struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func collectOutput(r io.Reader) *testOutput {
+
+	out := &testOutput{}
+	go func() {
+		p := make([]byte, 1024)
+		for {
+			n, err := r.Read(p)
+			out.mu.Lock()
+			out.buf.Write(p[:n])
+			out.mu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// waitFor 等待输出中出现 text
+func (o *testOutput) waitFor(t *testing.T, text string, timeout time.Duration) {
+
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		o.mu.Lock()
+		found, got := strings.Contains(o.buf.String(), text), o.buf.String()
+		o.mu.Unlock()
+		if found {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("output does not contain %q after %v: %q", text, timeout, got)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// waitStatus 等待后端结束并返回退出状态
+func waitStatus(t *testing.T, backend sessionBackend, timeout time.Duration) ExitStatus {
+
+	t.Helper()
+
+	done := make(chan ExitStatus, 1)
+	go func() { done <- backend.Wait() }()
+	select {
+	case status := <-done:
+		return status
+	case <-time.After(timeout):
+		t.Fatalf("backend did not exit within %v", timeout)
+		return ExitStatus{}
+	}
+}
+
+// waitSize 从 ch 读取下一次尺寸
+func waitSize(t *testing.T, ch <-chan [2]uint32, what string) [2]uint32 {
+
+	t.Helper()
+
+	select {
+	case size := <-ch:
+		return size
+	case <-time.After(15 * time.Second):
+		t.Fatalf("no %s received", what)
+		return [2]uint32{}
+	}
+}
+
+func TestSSHKeyFileAuth(t *testing.T) {
+
+	signer, keyFile := newTestKey(t)
+	server := newTestSSHServer(t, allowKey(signer.PublicKey()))
+	useKnownHosts(t, server.addr, server.hostKey.PublicKey())
+
+	profile := server.profile(t)
+	profile.KeyFile = keyFile
+	b, err := startSSH(profile, 100, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if size := waitSize(t, server.ptys, "pty-req"); size != [2]uint32{100, 30} {
+		t.Errorf("pty size = %v, want [100 30]", size)
+	}
+
+	out := collectOutput(b)
+	out.waitFor(t, "welcome", 5*time.Second)
+	if _, err := b.Write([]byte("ping\n")); err != nil {
+		t.Fatal(err)
+	}
+	out.waitFor(t, "ping", 5*time.Second)
+}
+
+func TestSSHAgentAuth(t *testing.T) {
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, _ := ssh.NewSignerFromKey(priv)
+
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix socket unavailable: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socket)
+
+	server := newTestSSHServer(t, allowKey(signer.PublicKey()))
+	useKnownHosts(t, server.addr, server.hostKey.PublicKey())
+
+	profile := server.profile(t)
+	profile.UseAgent = true
+	b, err := startSSH(profile, 80, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	collectOutput(b).waitFor(t, "welcome", 5*time.Second)
+}
+
+func TestSSHAuthFailures(t *testing.T) {
+
+	_, keyFile := newTestKey(t)
+	other, _ := newTestKey(t)
+
+	tests := []struct {
+		name      string
+		configure func(*ssh.ServerConfig)
+	}{
+		// 客户端不支持密码登录，只接受密码的服务器必须得到认证失败而不是挂起
+		{"password only server", func(config *ssh.ServerConfig) {
+			config.PasswordCallback = func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+				return nil, nil
+			}
+		}},
+		{"key not authorized", allowKey(other.PublicKey())},
+	}
+	for _, tt := range tests {
+		server := newTestSSHServer(t, tt.configure)
+		useKnownHosts(t, server.addr, server.hostKey.PublicKey())
+
+		profile := server.profile(t)
+		profile.KeyFile = keyFile
+		b, err := startSSH(profile, 80, 24)
+		if err == nil {
+			b.Close()
+			t.Errorf("%s: connected, want authentication error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), "SSH 认证失败") {
+			t.Errorf("%s: error = %v, want authentication failure", tt.name, err)
+		}
+	}
+}
+
+func TestSSHHostKeyVerification(t *testing.T) {
+
+	signer, keyFile := newTestKey(t)
+	server := newTestSSHServer(t, allowKey(signer.PublicKey()))
+
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherHostKey, _ := ssh.NewSignerFromKey(otherPriv)
+
+	tests := []struct {
+		name  string
+		known []ssh.PublicKey
+		want  string
+	}{
+		{"unknown host", nil, "未知主机"},
+		{"mismatched key", []ssh.PublicKey{otherHostKey.PublicKey()}, "可能存在中间人攻击"},
+	}
+	for _, tt := range tests {
+		useKnownHosts(t, server.addr, tt.known...)
+
+		profile := server.profile(t)
+		profile.KeyFile = keyFile
+		b, err := startSSH(profile, 80, 24)
+		if err == nil {
+			b.Close()
+			t.Errorf("%s: connected, want host key error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+		// 错误中带有服务器的指纹，供用户核对
+		if fp := ssh.FingerprintSHA256(server.hostKey.PublicKey()); !strings.Contains(err.Error(), fp) {
+			t.Errorf("%s: error %v does not show fingerprint %s", tt.name, err, fp)
+		}
+	}
+}
+
+func TestSSHExitStatus(t *testing.T) {
+
+	tests := []struct {
+		name string
+		exit func(ch ssh.Channel)
+		want ExitStatus
+	}{
+		{"exit code", func(ch ssh.Channel) {
+			_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{3}))
+		}, ExitStatus{Code: 3}},
+		{"exit signal", func(ch ssh.Channel) {
+			_, _ = ch.SendRequest("exit-signal", false, ssh.Marshal(struct {
+				Signal     string
+				CoreDumped bool
+				Message    string
+				Lang       string
+			}{"KILL", false, "", ""}))
+		}, ExitStatus{Code: -1, Signal: "SIGKILL"}},
+		// 没有退出状态说明连接中断，未开启重连时以 -1 结束
+		{"missing status", func(ssh.Channel) {}, ExitStatus{Code: -1}},
+	}
+
+	signer, keyFile := newTestKey(t)
+	for _, tt := range tests {
+		server := newTestSSHServer(t, allowKey(signer.PublicKey()))
+		server.exit = tt.exit
+		useKnownHosts(t, server.addr, server.hostKey.PublicKey())
+
+		profile := server.profile(t)
+		profile.KeyFile = keyFile
+		b, err := startSSH(profile, 80, 24)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		out := collectOutput(b)
+		if status := waitStatus(t, b, 10*time.Second); status != tt.want {
+			t.Errorf("%s: status = %+v, want %+v", tt.name, status, tt.want)
+		}
+		if tt.want.Code == -1 && tt.want.Signal == "" {
+			out.waitFor(t, "[SSH 连接已断开]", time.Second)
+		}
+		b.Close()
+	}
+}
+
+func TestSSHExitStatusErrors(t *testing.T) {
+
+	if status, exited := sshExitStatus(nil); !exited || status != (ExitStatus{Code: 0}) {
+		t.Errorf("sshExitStatus(nil) = %+v, %v", status, exited)
+	}
+	for _, err := range []error{&ssh.ExitMissingError{}, io.EOF, errors.New("connection reset")} {
+		if _, exited := sshExitStatus(err); exited {
+			t.Errorf("sshExitStatus(%v) reported exit", err)
+		}
+	}
+}
+
+func TestSSHKeepAliveReconnect(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("waits for keepalive timeouts")
+	}
+
+	signer, keyFile := newTestKey(t)
+	server := newTestSSHServer(t, allowKey(signer.PublicKey()))
+	server.dropFirstKeepAlive = true
+	useKnownHosts(t, server.addr, server.hostKey.PublicKey())
+
+	profile := server.profile(t)
+	profile.KeyFile = keyFile
+	profile.KeepAlive = 1
+	profile.Reconnect = true
+	b, err := startSSH(profile, 80, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	out := collectOutput(b)
+
+	if size := waitSize(t, server.ptys, "pty-req"); size != [2]uint32{80, 24} {
+		t.Fatalf("first pty size = %v, want [80 24]", size)
+	}
+	if err := b.Resize(30, 100); err != nil {
+		t.Fatal(err)
+	}
+	if size := waitSize(t, server.windows, "window-change"); size != [2]uint32{100, 30} {
+		t.Errorf("window-change = %v, want [100 30]", size)
+	}
+
+	// 第一个连接不响应保活，连续超时后断开并重连
+	out.waitFor(t, "重连", 15*time.Second)
+	if err := b.Resize(40, 132); err != nil {
+		t.Errorf("Resize while reconnecting: %v", err)
+	}
+
+	// 重连时重新执行 connect，按最新尺寸申请 PTY
+	if size := waitSize(t, server.ptys, "pty-req after reconnect"); size != [2]uint32{132, 40} {
+		t.Errorf("pty size after reconnect = %v, want [132 40]", size)
+	}
+	out.waitFor(t, "已重新连接", 5*time.Second)
+	if got := server.conns.Load(); got != 2 {
+		t.Errorf("server saw %d connections, want 2", got)
+	}
+
+	if _, err := b.Write([]byte("after-reconnect\n")); err != nil {
+		t.Fatal(err)
+	}
+	out.waitFor(t, "after-reconnect", 5*time.Second)
+
+	if err := b.Resize(50, 160); err != nil {
+		t.Fatal(err)
+	}
+	if size := waitSize(t, server.windows, "window-change after reconnect"); size != [2]uint32{160, 50} {
+		t.Errorf("window-change after reconnect = %v, want [160 50]", size)
+	}
+
+	b.Close()
+	if status := waitStatus(t, b, 5*time.Second); status.Code != -1 {
+		t.Errorf("status after Close = %+v, want code -1", status)
+	}
+}
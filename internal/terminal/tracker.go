@@ -0,0 +1,204 @@
+package terminal
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"edex-ui-golang/internal/models"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// sshOptionsWithValue ssh 中需要携带参数值的选项，解析目标主机时跳过其值
+const sshOptionsWithValue = "BbcDEeFIiJLlmOopQRSWw"
+
+// trackPollInterval 轮询前台进程状态的间隔
+const trackPollInterval = time.Second
+
+// track 周期性检查会话前台进程的状态，直到会话关闭
+func (ts *TerminalSession) track() {
+
+	ticker := time.NewTicker(trackPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ts.done:
+			return
+		case now := <-ticker.C:
+			ts.pollForeground()
+			ts.pollIdle(now)
+			ts.pollIdleTimeout(now)
+			ts.pollLimits()
+			if procCwdSupported {
+				ts.pollCwd()
+			}
+		}
+	}
+}
+
+// foregroundPid 返回前台进程的 PID，无法获取时退回 shell 本身
+func (ts *TerminalSession) foregroundPid() int {
+
+	ts.mu.RLock()
+	backend, running := ts.backend, ts.exitStatus == nil
+	ts.mu.RUnlock()
+
+	if backend == nil || !running {
+		return 0
+	}
+
+	if fg, ok := backend.(foregroundReporter); ok {
+		if pid, err := fg.ForegroundPid(); err == nil && pid > 0 {
+			return pid
+		}
+	}
+	if cmd := backend.Process(); cmd != nil && cmd.Process != nil {
+		return cmd.Process.Pid
+	}
+	return 0
+}
+
+// shellPid 返回会话 shell 本身的 PID
+func (ts *TerminalSession) shellPid() int {
+
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	if ts.Process != nil && ts.Process.Process != nil {
+		return ts.Process.Process.Pid
+	}
+	return 0
+}
+
+// pollForeground 读取前台进程的名称与命令行，变化时通知前端
+func (ts *TerminalSession) pollForeground() {
+
+	pid := ts.foregroundPid()
+	if pid <= 0 {
+		return
+	}
+
+	proc, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return
+	}
+	name, err := proc.Name()
+	if err != nil {
+		return
+	}
+	args, _ := proc.CmdlineSlice()
+
+	change := models.TerminalProcessChange{
+		SessionID: ts.ID,
+		Pid:       pid,
+		Name:      name,
+		Title:     processTitle(name, args),
+		Cmdline:   strings.Join(args, " "),
+		Busy:      pid != ts.shellPid(),
+	}
+
+	ts.mu.Lock()
+	changed := ts.foreground.Pid != change.Pid || ts.foreground.Title != change.Title
+	ts.foreground = change
+	ts.ProcessName = name
+	completion, finished := ts.noteForegroundLocked(change, time.Now())
+	ts.mu.Unlock()
+
+	if changed && ts.emit != nil {
+		ts.emit(EventProcessChanged, change)
+	}
+	if finished {
+		ts.reportCompletion(completion)
+	}
+}
+
+// processTitle 生成适合作为标签页标题的进程描述，例如 vim、ssh prod-1
+func processTitle(name string, args []string) string {
+
+	if len(args) > 0 {
+		name = filepath.Base(args[0])
+	}
+	name = strings.TrimPrefix(name, "-") // 登录 shell 的 argv[0] 以 - 开头
+	name = strings.TrimSuffix(name, ".exe")
+
+	if (name == "ssh" || name == "mosh") && len(args) > 1 {
+		if host := sshTarget(args[1:]); host != "" {
+			return name + " " + host
+		}
+	}
+	return name
+}
+
+// sshTarget 从 ssh 参数中找出目标主机
+func sshTarget(args []string) string {
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			if i+1 < len(args) {
+				return args[i+1]
+			}
+			return ""
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			return arg
+		}
+		// 形如 -p 22、-vp 22 的选项需要跳过其值；-p22 则不需要
+		if j := strings.IndexAny(arg[1:], sshOptionsWithValue); j == len(arg)-2 {
+			i++
+		}
+	}
+	return ""
+}
+
+// pollCwd 读取前台进程的工作目录
+//
+// 只有当进程的工作目录本身发生变化时才更新，避免覆盖 OSC 7 报告的远程目录。
+func (ts *TerminalSession) pollCwd() {
+
+	pid := ts.foregroundPid()
+	if pid <= 0 {
+		return
+	}
+
+	cwd, err := processCwd(pid)
+	if err != nil || cwd == ts.lastProcCwd {
+		return
+	}
+	ts.lastProcCwd = cwd
+	ts.setCwd(cwd, "proc")
+}
+
+// scanOutput 检查输出中的 OSC 序列（OSC 7 工作目录、OSC 133 命令标记）
+//
+// base 为 p 在输出流中的起始偏移。
+func (ts *TerminalSession) scanOutput(p []byte, base int64) {
+
+	ts.osc.feed(p, func(payload []byte, start, end int) {
+		if cwd, ok := parseOSC7(payload); ok {
+			ts.setCwd(cwd, "osc7")
+			return
+		}
+		if mark, ok := parseOSC133(payload); ok {
+			ts.handleShellMark(mark, base+int64(start), base+int64(end))
+		}
+	})
+}
+
+// setCwd 记录工作目录，变化时通知前端
+func (ts *TerminalSession) setCwd(cwd, source string) {
+
+	ts.mu.Lock()
+	changed := ts.Cwd != cwd
+	ts.Cwd = cwd
+	ts.mu.Unlock()
+
+	if changed && ts.emit != nil {
+		ts.emit(EventCwdChanged, models.TerminalCwdChange{
+			SessionID: ts.ID,
+			Cwd:       cwd,
+			Source:    source,
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package terminal
+
+import "testing"
+
+func TestProcessTitle(t *testing.T) {
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"vim", []string{"vim", "main.go"}, "vim"},
+		{"bash", []string{"-bash"}, "bash"},
+		{"zsh", nil, "zsh"},
+		{"node", []string{"/usr/local/bin/node", "server.js"}, "node"},
+		{"powershell.exe", nil, "powershell"},
+		{"ssh", []string{"ssh", "prod-1"}, "ssh prod-1"},
+		{"ssh", []string{"ssh", "-p", "2222", "deploy@prod-1"}, "ssh deploy@prod-1"},
+		{"ssh", []string{"ssh", "-p2222", "prod-1", "uptime"}, "ssh prod-1"},
+		{"ssh", []string{"ssh", "-A", "-i", "~/.ssh/id", "-o", "StrictHostKeyChecking=no", "bastion"}, "ssh bastion"},
+		{"ssh", []string{"ssh", "-vp", "22", "prod-1"}, "ssh prod-1"},
+		{"ssh", []string{"ssh", "-tt", "prod-1"}, "ssh prod-1"},
+		{"ssh", []string{"ssh", "--", "prod-1"}, "ssh prod-1"},
+		{"ssh", []string{"ssh", "-v"}, "ssh"},
+		{"ssh", []string{"ssh"}, "ssh"},
+		{"mosh", []string{"mosh", "dev"}, "mosh dev"},
+		{"scp", []string{"scp", "a", "host:b"}, "scp"},
+	}
+	for _, tt := range tests {
+		if got := processTitle(tt.name, tt.args); got != tt.want {
+			t.Errorf("processTitle(%q, %q) = %q, want %q", tt.name, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestSSHTarget(t *testing.T) {
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"host"}, "host"},
+		{[]string{"-l", "root", "host"}, "host"},
+		{[]string{"-lroot", "host"}, "host"},
+		{[]string{"-J", "jump", "-L", "8080:localhost:80", "host"}, "host"},
+		{[]string{"-4Cq", "host"}, "host"},
+		{[]string{"-Cp", "22", "host"}, "host"},
+		{[]string{"-", "host"}, "-"},
+		{[]string{"--"}, ""},
+		{[]string{"-p"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := sshTarget(tt.args); got != tt.want {
+			t.Errorf("sshTarget(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
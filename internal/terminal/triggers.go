@@ -0,0 +1,307 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"edex-ui-golang/internal/models"
+)
+
+// EventTriggerFired 输出触发规则命中，数据为 models.TriggerMatch
+const EventTriggerFired = "trigger-fired"
+
+// 触发规则动作
+const (
+	TriggerHighlight = "highlight" // 前端高亮命中的文本
+	TriggerNotify    = "notify"    // 前端弹出通知
+	TriggerSound     = "sound"     // 前端播放提示音
+	TriggerRespond   = "respond"   // 向会话写入预设内容，例如自动回答已知的提示
+)
+
+// 触发规则的执行限制
+const (
+	// triggerQueueSize 每个会话待检查的输出块数，队列满时丢弃，不阻塞 PTY 读取
+	triggerQueueSize = 64
+	// triggerMaxLine 参与匹配的单行最大字节数，超出的部分被忽略
+	triggerMaxLine = 4096
+	// defaultTriggerCooldown 同一规则在同一会话中两次触发的默认最短间隔
+	defaultTriggerCooldown = time.Second
+	// triggerBurst 与 triggerRate 每个会话的动作令牌桶：最多连续执行 triggerBurst 次，之后每秒恢复 triggerRate 次
+	triggerBurst = 20
+	triggerRate  = 5.0
+)
+
+// compiledTrigger 编译后的触发规则
+type compiledTrigger struct {
+	rule     models.OutputTrigger
+	re       *regexp.Regexp
+	cooldown time.Duration
+}
+
+// ValidateTrigger 校验触发规则
+func ValidateTrigger(rule models.OutputTrigger) error {
+
+	_, err := compileTrigger(rule)
+	return err
+}
+
+// compileTrigger 校验并编译触发规则
+func compileTrigger(rule models.OutputTrigger) (compiledTrigger, error) {
+
+	if rule.Pattern == "" {
+		return compiledTrigger{}, fmt.Errorf("触发规则的正则表达式不能为空")
+	}
+	re, err := regexp.Compile(rule.Pattern)
+	if err != nil {
+		return compiledTrigger{}, fmt.Errorf("无效的正则表达式 %q: %v", rule.Pattern, err)
+	}
+
+	switch rule.Action {
+	case TriggerHighlight, TriggerNotify, TriggerSound:
+	case TriggerRespond:
+		if rule.Response == "" {
+			return compiledTrigger{}, fmt.Errorf("respond 动作需要设置回复内容")
+		}
+	default:
+		return compiledTrigger{}, fmt.Errorf("未知的触发动作: %s", rule.Action)
+	}
+	if rule.Cooldown < 0 {
+		return compiledTrigger{}, fmt.Errorf("触发间隔不能为负数")
+	}
+
+	cooldown := time.Duration(rule.Cooldown) * time.Millisecond
+	if cooldown == 0 {
+		cooldown = defaultTriggerCooldown
+	}
+	if rule.Action == TriggerSound && rule.Sound == "" {
+		rule.Sound = "info"
+	}
+	return compiledTrigger{rule: rule, re: re, cooldown: cooldown}, nil
+}
+
+// SetTriggers 替换当前生效的触发规则，仅启用的规则会被编译
+//
+// 无效的规则（例如手工编辑配置文件写入的错误正则）被跳过并返回给调用方，
+// 其余规则照常生效。
+func (m *Manager) SetTriggers(rules []models.OutputTrigger) []models.OutputTrigger {
+
+	compiled := make([]compiledTrigger, 0, len(rules))
+	var invalid []models.OutputTrigger
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		trigger, err := compileTrigger(rule)
+		if err != nil {
+			log.Printf("跳过触发规则 %s: %v", rule.ID, err)
+			invalid = append(invalid, rule)
+			continue
+		}
+		compiled = append(compiled, trigger)
+	}
+
+	m.mu.Lock()
+	m.triggers = compiled
+	m.mu.Unlock()
+
+	log.Printf("已加载 %d 条输出触发规则", len(compiled))
+	return invalid
+}
+
+// activeTriggers 返回当前生效的触发规则，返回的切片不会被修改
+func (m *Manager) activeTriggers() []compiledTrigger {
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.triggers
+}
+
+// triggerChunk 等待检查的一段输出
+type triggerChunk struct {
+	data []byte
+	base int64
+}
+
+// triggerRunner 在独立协程中对会话输出执行触发规则
+type triggerRunner struct {
+	queue chan triggerChunk
+	// 当前行的原始内容及其在输出流中的起始偏移
+	line      []byte
+	lineStart int64
+	overflow  bool
+	// 已在当前未结束的行上触发过的规则，该行结束时不再重复触发
+	firedOnLine map[string]bool
+	lastFired   map[string]time.Time
+	// 动作令牌桶
+	tokens     float64
+	lastRefill time.Time
+	dropped    int
+}
+
+// newTriggerRunner 创建触发规则执行器
+func newTriggerRunner() *triggerRunner {
+
+	return &triggerRunner{
+		queue:       make(chan triggerChunk, triggerQueueSize),
+		firedOnLine: make(map[string]bool),
+		lastFired:   make(map[string]time.Time),
+		tokens:      triggerBurst,
+		lastRefill:  time.Now(),
+	}
+}
+
+// feedTriggers 将输出交给触发规则协程，队列已满时丢弃
+func (ts *TerminalSession) feedTriggers(p []byte, base int64) {
+
+	if ts.triggers == nil || ts.triggerRules == nil || len(ts.triggerRules()) == 0 {
+		return
+	}
+
+	data := make([]byte, len(p))
+	copy(data, p)
+	select {
+	case ts.triggers.queue <- triggerChunk{data: data, base: base}:
+	default:
+		ts.triggers.dropped++
+		if ts.triggers.dropped == 1 || ts.triggers.dropped%1000 == 0 {
+			log.Printf("会话 %s 输出过快，已跳过 %d 段输出的触发规则检查", ts.ID, ts.triggers.dropped)
+		}
+	}
+}
+
+// runTriggers 依次检查排队的输出，直到会话关闭
+func (ts *TerminalSession) runTriggers() {
+
+	r := ts.triggers
+	for {
+		select {
+		case <-ts.done:
+			return
+		case chunk := <-r.queue:
+			ts.scanTriggers(chunk, ts.triggerRules())
+		}
+	}
+}
+
+// scanTriggers 按行检查一段输出
+//
+// 完整的行在换行时检查；末尾未结束的行也会检查，以便响应不带换行的提示（如 Password:）。
+func (ts *TerminalSession) scanTriggers(chunk triggerChunk, rules []compiledTrigger) {
+
+	r := ts.triggers
+	p := chunk.data
+	offset := chunk.base
+
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			r.appendLine(p)
+			break
+		}
+
+		r.appendLine(p[:i])
+		ts.evaluateLine(rules, true)
+		offset += int64(i + 1)
+		p = p[i+1:]
+		r.line = r.line[:0]
+		r.lineStart = offset
+		r.overflow = false
+		r.firedOnLine = make(map[string]bool)
+	}
+
+	if len(r.line) > 0 {
+		ts.evaluateLine(rules, false)
+	}
+}
+
+// appendLine 追加当前行内容
+func (r *triggerRunner) appendLine(p []byte) {
+
+	if r.overflow {
+		return
+	}
+	if len(r.line)+len(p) > triggerMaxLine {
+		r.overflow = true
+		p = p[:triggerMaxLine-len(r.line)]
+	}
+	r.line = append(r.line, p...)
+}
+
+// evaluateLine 对当前行执行所有规则，complete 表示该行已经结束
+func (ts *TerminalSession) evaluateLine(rules []compiledTrigger, complete bool) {
+
+	r := ts.triggers
+	text := string(stripANSI(r.line))
+	if text == "" {
+		return
+	}
+
+	for _, trigger := range rules {
+		if r.firedOnLine[trigger.rule.ID] {
+			continue
+		}
+		loc := trigger.re.FindStringIndex(text)
+		if loc == nil {
+			continue
+		}
+		if !r.allow(trigger, time.Now()) {
+			continue
+		}
+		// 只有真正触发后才标记，被限流的规则在该行后续输出中仍可匹配
+		if !complete {
+			r.firedOnLine[trigger.rule.ID] = true
+		}
+		ts.fireTrigger(trigger, text, text[loc[0]:loc[1]], r.lineStart)
+	}
+}
+
+// allow 检查规则的触发间隔与会话的动作令牌桶
+func (r *triggerRunner) allow(trigger compiledTrigger, now time.Time) bool {
+
+	if last, ok := r.lastFired[trigger.rule.ID]; ok && now.Sub(last) < trigger.cooldown {
+		return false
+	}
+
+	r.tokens += now.Sub(r.lastRefill).Seconds() * triggerRate
+	if r.tokens > triggerBurst {
+		r.tokens = triggerBurst
+	}
+	r.lastRefill = now
+	if r.tokens < 1 {
+		return false
+	}
+
+	r.tokens--
+	r.lastFired[trigger.rule.ID] = now
+	return true
+}
+
+// fireTrigger 执行规则动作并通知前端
+func (ts *TerminalSession) fireTrigger(trigger compiledTrigger, line, match string, offset int64) {
+
+	rule := trigger.rule
+	if rule.Action == TriggerRespond {
+		// 进程已退出时不写入，避免回复中的换行重启 shell
+		if !ts.running() {
+			return
+		}
+		ts.write([]byte(rule.Response))
+	}
+
+	if ts.emit != nil {
+		ts.emit(EventTriggerFired, models.TriggerMatch{
+			SessionID: ts.ID,
+			TriggerID: rule.ID,
+			Name:      rule.Name,
+			Action:    rule.Action,
+			Line:      line,
+			Match:     match,
+			Offset:    offset,
+			Color:     rule.Color,
+			Sound:     rule.Sound,
+		})
+	}
+}
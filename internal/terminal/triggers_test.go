@@ -0,0 +1,190 @@
+package terminal
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"edex-ui-golang/internal/models"
+)
+
+// triggerHit 触发的规则、命中的文本及所在行的偏移
+type triggerHit struct {
+	id     string
+	match  string
+	offset int64
+}
+
+// newTriggerSession 创建只用于执行触发规则的会话，返回的函数获取已触发的记录
+func newTriggerSession() (*TerminalSession, func() []triggerHit) {
+
+	var hits []triggerHit
+	ts := newTerminalSession("triggers", minScrollbackSize)
+	ts.triggers = newTriggerRunner()
+	ts.emit = func(event string, data interface{}) {
+		if match, ok := data.(models.TriggerMatch); ok && event == EventTriggerFired {
+			hits = append(hits, triggerHit{match.TriggerID, match.Match, match.Offset})
+		}
+	}
+	return ts, func() []triggerHit { return hits }
+}
+
+// mustCompileTriggers 编译测试用的规则
+func mustCompileTriggers(t *testing.T, rules ...models.OutputTrigger) []compiledTrigger {
+
+	t.Helper()
+
+	var compiled []compiledTrigger
+	for _, rule := range rules {
+		trigger, err := compileTrigger(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compiled = append(compiled, trigger)
+	}
+	return compiled
+}
+
+// scanTriggerStream 将 stream 按 chunks 给出的长度切分后依次检查
+func scanTriggerStream(stream string, chunks []int, rules []compiledTrigger) []triggerHit {
+
+	ts, hits := newTriggerSession()
+	base := 0
+	for _, n := range chunks {
+		ts.scanTriggers(triggerChunk{data: []byte(stream[base : base+n]), base: int64(base)}, rules)
+		base += n
+	}
+	return hits()
+}
+
+func TestTriggerLineAssemblyChunkBoundaries(t *testing.T) {
+
+	rules := mustCompileTriggers(t,
+		models.OutputTrigger{ID: "err", Pattern: "error", Action: TriggerHighlight},
+		models.OutputTrigger{ID: "pw", Pattern: "Password:", Action: TriggerNotify},
+	)
+	tests := []struct {
+		name   string
+		stream string
+		want   []triggerHit
+	}{
+		{"complete line", "ok\nan error here\n", []triggerHit{{"err", "error", 3}}},
+		{"prompt without newline", "login\nPassword: ", []triggerHit{{"pw", "Password:", 6}}},
+		{"escape sequences stripped", "\x1b[31merr\x1b[0mor\r\n", []triggerHit{{"err", "error", 0}}},
+		{"two rules on separate lines", "error\nPassword:", []triggerHit{{"err", "error", 0}, {"pw", "Password:", 6}}},
+		{"match inside word", "errors are spelled e-r-r\n", []triggerHit{{"err", "error", 0}}},
+		{"empty lines", "\n\n\nerror", []triggerHit{{"err", "error", 3}}},
+	}
+
+	for _, tt := range tests {
+		// 整段送入
+		if got := scanTriggerStream(tt.stream, []int{len(tt.stream)}, rules); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: whole = %v, want %v", tt.name, got, tt.want)
+		}
+		// 在每个位置切成两段
+		for k := 0; k <= len(tt.stream); k++ {
+			if got := scanTriggerStream(tt.stream, []int{k, len(tt.stream) - k}, rules); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: split at %d = %v, want %v", tt.name, k, got, tt.want)
+			}
+		}
+		// 逐字节送入
+		ones := make([]int, len(tt.stream))
+		for i := range ones {
+			ones[i] = 1
+		}
+		if got := scanTriggerStream(tt.stream, ones, rules); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: byte by byte = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTriggerFiresOncePerPartialLine(t *testing.T) {
+
+	rules := mustCompileTriggers(t, models.OutputTrigger{ID: "pw", Pattern: "Password:", Action: TriggerNotify, Cooldown: 1})
+	ts, hits := newTriggerSession()
+
+	// 未结束的行每次收到输出都会重新检查，但只触发一次；换行后重新计算
+	for i, chunk := range []string{"Password:", " ", "hunter2", "\n", "Password:\n"} {
+		time.Sleep(2 * time.Millisecond)
+		ts.scanTriggers(triggerChunk{data: []byte(chunk), base: int64(i)}, rules)
+	}
+	if got := len(hits()); got != 2 {
+		t.Errorf("fired %d times, want 2: %v", got, hits())
+	}
+}
+
+func TestTriggerAllow(t *testing.T) {
+
+	start := time.Now()
+
+	// 未设置间隔时使用默认值
+	r := newTriggerRunner()
+	r.lastRefill = start
+	trigger := mustCompileTriggers(t, models.OutputTrigger{ID: "a", Pattern: "a", Action: TriggerHighlight})[0]
+	if trigger.cooldown != defaultTriggerCooldown {
+		t.Fatalf("cooldown = %v, want %v", trigger.cooldown, defaultTriggerCooldown)
+	}
+	steps := []struct {
+		at   time.Duration
+		want bool
+	}{
+		{0, true},
+		{defaultTriggerCooldown / 2, false},
+		{defaultTriggerCooldown - time.Millisecond, false},
+		{defaultTriggerCooldown, true},
+	}
+	for _, step := range steps {
+		if got := r.allow(trigger, start.Add(step.at)); got != step.want {
+			t.Errorf("cooldown at %v = %v, want %v", step.at, got, step.want)
+		}
+	}
+
+	// 令牌桶：连续执行 triggerBurst 次后被限制，之后按 triggerRate 恢复，且不超过 triggerBurst
+	r = newTriggerRunner()
+	r.lastRefill = start
+	allowed := func(n int, at time.Duration) int {
+		count := 0
+		for i := 0; i < n; i++ {
+			rule := models.OutputTrigger{ID: fmt.Sprintf("r%d-%d", at, i), Pattern: "x", Action: TriggerHighlight}
+			if r.allow(mustCompileTriggers(t, rule)[0], start.Add(at)) {
+				count++
+			}
+		}
+		return count
+	}
+	if got := allowed(triggerBurst+5, 0); got != triggerBurst {
+		t.Errorf("burst allowed %d, want %d", got, triggerBurst)
+	}
+	if got := allowed(triggerBurst, time.Second); got != int(triggerRate) {
+		t.Errorf("after 1s allowed %d, want %d", got, int(triggerRate))
+	}
+	if got := allowed(triggerBurst+5, time.Hour); got != triggerBurst {
+		t.Errorf("after refill allowed %d, want %d", got, triggerBurst)
+	}
+}
+
+func TestSetTriggersSkipsInvalidRules(t *testing.T) {
+
+	m := NewManager(&models.Settings{})
+	defer m.Close()
+
+	invalid := m.SetTriggers([]models.OutputTrigger{
+		{ID: "ok", Pattern: "done", Action: TriggerNotify, Enabled: true},
+		{ID: "bad-pattern", Pattern: "(", Action: TriggerNotify, Enabled: true},
+		{ID: "bad-action", Pattern: "x", Action: "explode", Enabled: true},
+		{ID: "disabled", Pattern: "(", Action: TriggerNotify},
+	})
+
+	var ids []string
+	for _, rule := range invalid {
+		ids = append(ids, rule.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"bad-pattern", "bad-action"}) {
+		t.Errorf("invalid = %v, want [bad-pattern bad-action]", ids)
+	}
+	active := m.activeTriggers()
+	if len(active) != 1 || active[0].rule.ID != "ok" {
+		t.Errorf("active triggers = %v, want only ok", active)
+	}
+}
@@ -2,89 +2,76 @@ package terminal
 
 import (
 	"context"
-	"edex-ui-golang/internal/utils"
-	"fmt"
-	"io"
 	"log"
+	"net"
 	"net/http"
-	"os/exec"
-	"runtime"
 	"strconv"
-	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"edex-ui-golang/internal/models"
 	"github.com/gorilla/websocket"
-	"github.com/iyzyi/aiopty/pty"
 )
 
 // WebSocketManager WebSocket管理器
 type WebSocketManager struct {
-	manager  *Manager
-	server   *http.Server
-	sessions map[string]*TerminalSession
-	upgrader websocket.Upgrader
-	mu       sync.RWMutex
-}
-
-// TerminalSession 终端会话
-type TerminalSession struct {
-	ID      string
-	Process *exec.Cmd
-	mu      sync.RWMutex
-	closed  bool
-	// 统一通过 PTY 交互
-	ptyInst     *pty.Pty
-	ProcessName string
-}
-
-// wsBinaryWriter 将 WebSocket 作为 io.Writer，按二进制帧发送
-type wsBinaryWriter struct {
-	conn *websocket.Conn
-}
-
-func (w *wsBinaryWriter) Write(p []byte) (int, error) {
-
-	if err := w.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
-		return 0, err
-	}
-	return len(p), nil
+	manager   *Manager
+	server    *http.Server
+	sessions  *SessionRegistry
+	shares    *shareTable
+	broadcast *broadcastGroup
+	upgrader  websocket.Upgrader
+	addr      atomic.Pointer[net.TCPAddr] // 实际监听地址，服务器启动后有效
 }
 
 // NewWebSocketManager 创建WebSocket管理器
 func NewWebSocketManager(manager *Manager) *WebSocketManager {
 
-	upgrader := websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true
-		},
+	wsm := &WebSocketManager{
+		manager:   manager,
+		sessions:  newSessionRegistry(),
+		shares:    newShareTable(),
+		broadcast: newBroadcastGroup(),
+	}
+	wsm.upgrader = websocket.Upgrader{
+		CheckOrigin:     wsm.checkOrigin,
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
+		Subprotocols:    []string{protocolV1},
 	}
 
-	return &WebSocketManager{
-		manager:  manager,
-		sessions: make(map[string]*TerminalSession),
-		upgrader: upgrader,
-	}
+	return wsm
 }
 
 // StartWebSocketServer 启动WebSocket服务器
-func (wsm *WebSocketManager) StartWebSocketServer(port int) error {
+//
+// 默认只监听本机回环地址；监听所有网卡必须在设置中显式开启。
+func (wsm *WebSocketManager) StartWebSocketServer(host string, port int) error {
 	// WebSocket端点
 	mux := http.NewServeMux()
 
+	// 可选的 permessage-deflate 压缩，需在服务启动前确定
+	wsm.upgrader.EnableCompression = wsm.manager.settings.TerminalCompression
+
 	mux.HandleFunc("/webterminal", wsm.handleWebSocket)
 	wsm.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", port),
+		Addr:    net.JoinHostPort(host, strconv.Itoa(port)),
 		Handler: mux,
 	}
 
+	// 同步监听，端口被占用等错误可以直接返回给调用方
+	listener, err := net.Listen("tcp", wsm.server.Addr)
+	if err != nil {
+		return err
+	}
+	if addr, ok := listener.Addr().(*net.TCPAddr); ok {
+		wsm.addr.Store(addr)
+	}
+
 	go func() {
 
-		log.Printf("WebSocket终端服务器启动在端口 %d", port)
-		if err := wsm.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("WebSocket终端服务器启动在 %s", wsm.server.Addr)
+		if err := wsm.server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			log.Printf("WebSocket服务器错误: %v", err)
 		}
 	}()
@@ -92,11 +79,11 @@ func (wsm *WebSocketManager) StartWebSocketServer(port int) error {
 	return nil
 }
 
-// StopWebSocketServer 停止WebSocket服务器
-func (wsm *WebSocketManager) StopWebSocketServer() error {
+// StopWebSocketServer 停止WebSocket服务器，最多等待 timeout 让进行中的请求结束
+func (wsm *WebSocketManager) StopWebSocketServer(timeout time.Duration) error {
 
 	if wsm.server != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 		return wsm.server.Shutdown(ctx)
 	}
@@ -104,8 +91,42 @@ func (wsm *WebSocketManager) StopWebSocketServer() error {
 }
 
 // handleWebSocket 处理WebSocket连接
+//
+// 连接必须携带查询参数 token（每次启动随机生成，由 App 交给前端），
+// 或者携带只读分享令牌 share，此时以观察者身份附加到被分享的会话。
+// 客户端可通过查询参数 session 指定会话 ID：若会话已存在则重新附加并回放最近输出，
+// 否则以该 ID 创建新会话。未指定时生成随机 ID。连接断开不会结束会话。
 func (wsm *WebSocketManager) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 
+	// 持有分享令牌的连接只能以观察者身份附加到对应会话
+	var grant *shareGrant
+	if shareToken := r.URL.Query().Get("share"); shareToken != "" {
+		var ok bool
+		if grant, ok = wsm.shares.lookup(shareToken); !ok {
+			log.Printf("拒绝无效的分享连接: %s", r.RemoteAddr)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	} else if !wsm.authorize(r) {
+		log.Printf("拒绝未授权的终端连接: %s", r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session")
+	if grant != nil {
+		if _, exists := wsm.sessions.Get(grant.SessionID); !exists {
+			http.Error(w, "session closed", http.StatusGone)
+			return
+		}
+		sessionID = grant.SessionID
+	} else if sessionID != "" {
+		if err := validateSessionID(sessionID); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	conn, err := wsm.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket升级失败: %v", err)
@@ -113,28 +134,36 @@ func (wsm *WebSocketManager) handleWebSocket(w http.ResponseWriter, r *http.Requ
 	}
 	defer conn.Close()
 
-	sessionID := r.RemoteAddr + "_" + fmt.Sprintf("%d", time.Now().UnixNano())
-	log.Printf("新的WebSocket连接: %s", sessionID)
-
-	// 创建终端会话
-	session := &TerminalSession{
-		ID: sessionID,
-	}
-
-	// 启动终端进程
-	if err := session.startProcess(wsm.manager.terminal); err != nil {
+	var session *TerminalSession
+	created := false
+	if grant != nil {
+		var exists bool
+		if session, exists = wsm.sessions.Get(sessionID); !exists {
+			return
+		}
+	} else if session, created, err = wsm.getOrCreateSession(sessionID); err != nil {
 		log.Printf("启动终端进程失败: %v", err)
-		conn.Close()
+		rejectConnection(conn, err)
 		return
 	}
 
-	// 存储会话
-	wsm.mu.Lock()
-	wsm.sessions[sessionID] = session
-	wsm.mu.Unlock()
+	switch {
+	case grant != nil:
+		log.Printf("观察者以只读方式附加到会话: %s (%s)", session.ID, r.RemoteAddr)
+	case created:
+		log.Printf("新的WebSocket连接: %s (%s)，已创建会话", session.ID, r.RemoteAddr)
+	default:
+		log.Printf("WebSocket重新附加到会话: %s (%s)", session.ID, r.RemoteAddr)
+	}
 
-	// 启动进程输出读取循环
-	go session.streamProcessOutput(conn)
+	client := newWSClient(conn, r.RemoteAddr)
+	if grant != nil {
+		client.readOnly = true
+		client.shareToken = grant.Token
+	}
+	session.attach(client)
+	defer session.detach(client)
+	defer client.shutdown()
 
 	// 处理WebSocket消息
 	for {
@@ -146,118 +175,82 @@ func (wsm *WebSocketManager) handleWebSocket(w http.ResponseWriter, r *http.Requ
 			break
 		}
 
-		// 优先检测 resize 控制序列 ESC[8;rows;cols t
-		data := string(message)
-		if strings.HasPrefix(data, "\x1b[8;") && strings.HasSuffix(data, "t") {
-			body := strings.TrimPrefix(data, "\x1b[8;")
-			body = strings.TrimSuffix(body, "t")
-			parts := strings.Split(body, ";")
-			if len(parts) == 2 && session.ptyInst != nil {
-				if r, errR := strconv.Atoi(strings.TrimSpace(parts[0])); errR == nil {
-					if c, errC := strconv.Atoi(strings.TrimSpace(parts[1])); errC == nil {
-						_ = session.ptyInst.SetSize(&pty.WinSize{Rows: uint16(r), Cols: uint16(c)})
-						continue
-					}
-				}
+		// 观察者：只处理心跳，输入与调整尺寸全部丢弃
+		if client.readOnly {
+			if client.protocol >= ProtocolVersion && msgType == websocket.TextMessage {
+				session.handleViewerControl(client, message)
 			}
+			continue
 		}
 
-		// 写入进程输入（PTY）：
-		// - 文本帧：若不是 resize 控制序列，则作为输入写入 PTY
-		// - 二进制帧：直接写入 PTY
-		if session.ptyInst != nil {
-			if msgType == websocket.BinaryMessage {
-				_, _ = session.ptyInst.Write(message)
-			} else if msgType == websocket.TextMessage {
-				// 已在上方对 resize 做了拦截并 continue；其他文本即为正常输入
-				_, _ = session.ptyInst.Write(message)
+		// v1 客户端：文本帧为控制消息，二进制帧为终端输入
+		if client.protocol >= ProtocolVersion {
+			if msgType == websocket.TextMessage {
+				session.handleControl(client, message)
+			} else {
+				wsm.input(session, message)
 			}
+			continue
 		}
-	}
 
-	// 清理会话
-	wsm.mu.Lock()
-	if session, exists := wsm.sessions[sessionID]; exists {
-		session.close()
-		delete(wsm.sessions, sessionID)
+		// 旧版客户端：优先检测 resize 控制序列 ESC[8;rows;cols t，
+		// 其余文本帧与二进制帧均作为输入写入 PTY
+		if rows, cols, ok := parseLegacyResize(string(message)); ok {
+			_ = session.resize(rows, cols)
+			continue
+		}
+		if msgType == websocket.BinaryMessage || msgType == websocket.TextMessage {
+			wsm.input(session, message)
+		}
 	}
-	wsm.mu.Unlock()
 
-	log.Printf("WebSocket连接断开: %s", sessionID)
+	log.Printf("WebSocket连接断开: %s，会话继续在后台运行", session.ID)
 }
 
-// startProcess 启动终端进程（统一使用 PTY，包括 Windows）
-func (ts *TerminalSession) startProcess(terminal *models.Terminal) error {
-	// 解析 shell 与参数
-
-	shell := terminal.Shell
-	shellType := pty.AUTO
-	AppDir, _ := utils.GetAppDir()
-	var args []string
-	if terminal.Params != "" {
-		args = strings.Fields(terminal.Params)
-	} else {
-		switch runtime.GOOS {
-		case "windows":
-			shell = "cmd.exe"
-			args = []string{"cmd.exe", "/c", "cd", "/d", AppDir, "&&", "powershell.exe"}
-		case "linux", "darwin":
-			shell = "bash"
-		}
-	}
-	log.Println(terminal.Cwd)
-	// 生成 PTY 启动参数
-	opt := &pty.Options{
-		Path: shell,
-		Args: args,
-		Dir:  "",
-		Env:  nil,
-		Size: &pty.WinSize{Cols: 120, Rows: 20},
-		Type: shellType,
-	}
-	// 打开 PTY
-	p, err := pty.OpenWithOptions(opt)
-	if err != nil {
-		return fmt.Errorf("启动 PTY 失败: %v", err)
+// getOrCreateSession 按 ID 查找会话，不存在时以默认 shell 创建
+func (wsm *WebSocketManager) getOrCreateSession(sessionID string) (*TerminalSession, bool, error) {
+
+	if sessionID == "" {
+		sessionID = newSessionID()
 	}
-	// 记录 PTY 与底层命令（若可获取）
-	ts.ptyInst = p
-	return nil
+	return wsm.sessions.getOrCreate(sessionID, func(id string) (*TerminalSession, error) {
+		return wsm.manager.newLocalSession(id, models.SessionOptions{})
+	})
 }
 
-// streamProcessOutput 将子进程输出以二进制帧写入到 WebSocket
-func (ts *TerminalSession) streamProcessOutput(conn *websocket.Conn) {
-	// 通过 io.Copy 持续将 PTY 输出转发为 WebSocket 二进制帧
+// removeSession 从会话表中移除并关闭会话
+func (wsm *WebSocketManager) removeSession(session *TerminalSession) {
 
-	writer := &wsBinaryWriter{conn: conn}
-	buffer := make([]byte, 4096)
-	if _, err := io.CopyBuffer(writer, ts.ptyInst, buffer); err != nil && err != io.EOF {
-		if !ts.closed {
-			log.Printf("转发 PTY 到 WebSocket 失败: %v", err)
-		}
-	}
+	wsm.sessions.remove(session)
+	wsm.revokeSessionShares(session.ID)
+	wsm.RemoveFromBroadcast(session.ID)
+	session.close()
+	log.Printf("会话已结束: %s", session.ID)
 }
 
-// close 关闭会话
-func (ts *TerminalSession) close() {
-
-	ts.mu.Lock()
-	defer ts.mu.Unlock()
+// startPlayback 创建回放录像的只读会话
+func (wsm *WebSocketManager) startPlayback(path string, speed float64) (*TerminalSession, error) {
 
-	if ts.closed {
-		return
+	session, err := newPlaybackSession("play-"+newSessionID(), path, speed, wsm.manager.scrollbackSize())
+	if err != nil {
+		return nil, err
+	}
+	if err := wsm.sessions.add(session); err != nil {
+		return nil, err
 	}
 
-	ts.closed = true
+	go session.play(wsm.removeSession)
+	return session, nil
+}
 
-	// 关闭 PTY
-	if ts.ptyInst != nil {
-		ts.ptyInst.Close()
-	}
+// GetSession 按 ID 获取会话
+func (wsm *WebSocketManager) GetSession(sessionID string) (*TerminalSession, bool) {
 
-	// 终止进程
-	if ts.Process != nil {
-		ts.Process.Process.Kill()
-		ts.Process.Wait()
-	}
+	return wsm.sessions.Get(sessionID)
+}
+
+// SessionIDs 返回所有会话 ID
+func (wsm *WebSocketManager) SessionIDs() []string {
+
+	return wsm.sessions.IDs()
 }